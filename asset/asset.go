@@ -0,0 +1,146 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ErrAssetNotFound represents unknown logical asset name on manifest lookup
+var ErrAssetNotFound = errors.New("asset: Asset was not found on the manifest")
+
+// HashLength define the number of hex characters used as fingerprint
+const HashLength = 12
+
+// Manifest maps logical asset names to fingerprinted file paths.
+type Manifest struct {
+	// Prefix is the URL path prefix where the manifest handler is mounted
+	Prefix string
+	fsys   fs.FS
+	mu     sync.RWMutex
+	names  map[string]string
+	files  map[string]entry
+}
+
+// entry store fingerprinted file information
+type entry struct {
+	name string
+	hash string
+}
+
+// New creates manifest from the file system and immediately walks it.
+func New(fsys fs.FS, prefix string) (*Manifest, error) {
+	m := &Manifest{
+		Prefix: prefix,
+		fsys:   fsys,
+	}
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load walks the file system and rebuilds the manifest. It is safe to call
+// Load while serving requests, for example after assets were rebuilt.
+func (m *Manifest) Load() error {
+	names := make(map[string]string)
+	files := make(map[string]entry)
+	err := fs.WalkDir(m.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		hash, err := hashFile(m.fsys, name)
+		if err != nil {
+			return err
+		}
+		fingerprinted := fingerprint(name, hash)
+		names[name] = fingerprinted
+		files[fingerprinted] = entry{name: name, hash: hash}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Swap manifest content
+	m.mu.Lock()
+	m.names = names
+	m.files = files
+	m.mu.Unlock()
+	return nil
+}
+
+// Path gets fingerprinted path of a logical asset name.
+func (m *Manifest) Path(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.names[strings.TrimPrefix(name, "/")]; ok {
+		return p, nil
+	}
+	return "", ErrAssetNotFound
+}
+
+// URL gets fingerprinted URL of a logical asset name. Unknown asset names
+// fall back to the non-fingerprinted URL so the page still renders.
+func (m *Manifest) URL(name string) string {
+	p, err := m.Path(name)
+	if err != nil {
+		p = strings.TrimPrefix(name, "/")
+	}
+	return path.Join("/", m.Prefix, p)
+}
+
+// Files gets a copy of the logical name to fingerprinted path mapping.
+func (m *Manifest) Files() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	files := make(map[string]string, len(m.names))
+	for name, p := range m.names {
+		files[name] = p
+	}
+	return files
+}
+
+// FuncMap gets template functions that expose Manifest.URL as asset.
+func (m *Manifest) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": m.URL,
+	}
+}
+
+// lookup resolves fingerprinted path into its file entry.
+func (m *Manifest) lookup(p string) (entry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.files[p]
+	return e, ok
+}
+
+// hashFile computes hex encoded SHA-256 hash of a file content.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:HashLength], nil
+}
+
+// fingerprint inserts hash before the file extension.
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package asset
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testManifest(t *testing.T, prefix string) (*Manifest, fstest.MapFS) {
+	fsys := fstest.MapFS{
+		"css/app.css": {Data: []byte("body{}")},
+		"js/app.js":   {Data: []byte("run()")},
+	}
+	m, err := New(fsys, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, fsys
+}
+
+func TestManifestURL(t *testing.T) {
+	m, fsys := testManifest(t, "/assets/")
+	u := m.URL("css/app.css")
+	if !strings.HasPrefix(u, "/assets/css/app.") || !strings.HasSuffix(u, ".css") || len(u) != len("/assets/css/app..css")+HashLength {
+		t.Fatalf("URL %q", u)
+	}
+	if u := m.URL("/missing.png"); u != "/assets/missing.png" {
+		t.Fatalf("unknown asset URL %q", u)
+	}
+	if _, err := m.Path("missing.png"); err != ErrAssetNotFound {
+		t.Fatalf("got %v", err)
+	}
+	// Reloading picks up changed content
+	fsys["css/app.css"] = &fstest.MapFile{Data: []byte("body{color:red}")}
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if m.URL("css/app.css") == u {
+		t.Fatal("fingerprint did not change")
+	}
+	if files := m.Files(); len(files) != 2 {
+		t.Fatalf("files %v", files)
+	}
+}
+
+func TestManifestServeHTTP(t *testing.T) {
+	m, _ := testManifest(t, "/assets")
+	fingerprinted := m.URL("js/app.js")
+	tests := []struct {
+		method, path string
+		code         int
+		cache        string
+	}{
+		{"GET", fingerprinted, 200, CacheControl},
+		{"HEAD", fingerprinted, 200, CacheControl},
+		{"GET", "/assets/js/app.js", 200, "no-cache"},
+		{"GET", "/assets/js/missing.js", 404, ""},
+		{"GET", "/assetsjs/app.js", 404, ""},
+		{"GET", "/assetsfoo" + strings.TrimPrefix(fingerprinted, "/assets"), 404, ""},
+		{"GET", "/assets", 404, ""},
+		{"POST", fingerprinted, 405, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Cache-Control") != tt.cache {
+			t.Errorf("%s %s: got %d with Cache-Control %q", tt.method, tt.path, w.Code, w.Header().Get("Cache-Control"))
+		}
+		if tt.code == 200 && tt.method == "GET" && w.Body.String() != "run()" {
+			t.Errorf("%s %s: body %q", tt.method, tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestManifestRootPrefix(t *testing.T) {
+	m, _ := testManifest(t, "")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", m.URL("css/app.css"), nil))
+	if w.Code != 200 || w.Body.String() != "body{}" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package asset builds cache-busting URLs for static files. It walks an fs.FS,
+computes the content hash of every file, and keeps a manifest that maps the
+logical file name to its fingerprinted path, for example css/app.css to
+css/app.1a2b3c4d5e6f.css. The manifest is also a static file handler that
+serves fingerprinted paths with far-future cache headers.
+
+	assets, err := asset.New(os.DirFS("public"), "/assets/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.PathPrefix("/assets/").Handler(assets)
+
+Use Manifest.URL in handlers, or register Manifest.FuncMap to call the asset
+function from templates.
+
+	<link rel="stylesheet" href="{{asset "css/app.css"}}">
+*/
+package asset
+
+// This file is intentionally left blank for Godoc documentation.
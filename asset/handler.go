@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package asset
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// CacheControl define cache header for fingerprinted asset responses
+var CacheControl = "public, max-age=31536000, immutable"
+
+// ServeHTTP implements http.Handler for static asset files. Fingerprinted
+// paths are served with far-future cache headers while logical names are
+// served with revalidation so stale references still work.
+func (m *Manifest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 Method Not Allowed", 405)
+		return
+	}
+	// Strip mount prefix from request path, the prefix must end at a path
+	// segment boundary
+	prefix := path.Join("/", m.Prefix)
+	p, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok || prefix != "/" && p != "" && p[0] != '/' {
+		http.NotFound(w, r)
+		return
+	}
+	p = strings.TrimPrefix(p, "/")
+	// Resolve requested path
+	name := p
+	if e, ok := m.lookup(p); ok {
+		name = e.name
+		w.Header().Set("Cache-Control", CacheControl)
+		w.Header().Set("ETag", `"`+e.hash+`"`)
+	} else if _, err := m.Path(p); err == nil {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+	m.serveFile(w, r, name)
+}
+
+// serveFile writes file content from the manifest file system.
+func (m *Manifest) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error", 500)
+		return
+	}
+	// Use seekable file directly, otherwise buffer the content
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, "500 Internal Server Error", 500)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// APIKey authenticates requests with an API key sent in a request header
+// or a query parameter.
+type APIKey struct {
+	// Header is the request header of the key, defaults to X-API-Key
+	Header string
+	// Query is the query parameter of the key, disabled if empty
+	Query string
+	// Lookup gets principal of the key, or nil for unknown keys
+	Lookup func(ctx context.Context, key string) (*route.Principal, error)
+}
+
+// NewAPIKey creates API key authenticator with the lookup function.
+func NewAPIKey(lookup func(ctx context.Context, key string) (*route.Principal, error)) *APIKey {
+	return &APIKey{
+		Header: "X-API-Key",
+		Lookup: lookup,
+	}
+}
+
+// Authenticate implements route.Authenticator interface.
+func (a *APIKey) Authenticate(r *http.Request) (*route.Principal, error) {
+	var key string
+	if a.Header != "" {
+		key = r.Header.Get(a.Header)
+	}
+	if key == "" && a.Query != "" {
+		key = r.URL.Query().Get(a.Query)
+	}
+	if key == "" {
+		return nil, route.ErrNoCredentials
+	}
+	p, err := a.Lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if p.Scheme == "" {
+		p.Scheme = "apikey"
+	}
+	return p, nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mandala/omnibus/route"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials represents wrong username, password or key
+var ErrInvalidCredentials = errors.New("auth: Invalid credentials")
+
+// Basic authenticates requests with HTTP Basic authentication.
+type Basic struct {
+	Realm string
+	// Check verifies the username and password pair
+	Check func(username, password string) bool
+}
+
+// NewBasic creates Basic authenticator with plain text username and
+// password pairs. Passwords are compared in constant time.
+func NewBasic(realm string, users map[string]string) *Basic {
+	hashed := make(map[string][32]byte, len(users))
+	for user, pass := range users {
+		hashed[user] = sha256.Sum256([]byte(pass))
+	}
+	return &Basic{
+		Realm: realm,
+		Check: func(username, password string) bool {
+			// Compare hashes so the password length is not leaked
+			want, ok := hashed[username]
+			got := sha256.Sum256([]byte(password))
+			return subtle.ConstantTimeCompare(want[:], got[:]) == 1 && ok
+		},
+	}
+}
+
+// LoadHtpasswd creates Basic authenticator from htpasswd file with bcrypt
+// hashed passwords, as created by htpasswd -B.
+func LoadHtpasswd(realm, path string) (*Basic, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	users := make(map[string][]byte)
+	cost := 0
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("auth: %s:%d: Malformed htpasswd entry", path, n)
+		}
+		hash := line[i+1:]
+		c, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s:%d: Password is not hashed with bcrypt", path, n)
+		}
+		if c > cost {
+			cost = c
+		}
+		users[line[:i]] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Unknown users are compared against a dummy hash of the same cost, so
+	// the response time does not reveal valid usernames
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	dummy, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+	if err != nil {
+		return nil, err
+	}
+	return &Basic{
+		Realm: realm,
+		Check: func(username, password string) bool {
+			hash, ok := users[username]
+			if !ok {
+				hash = dummy
+			}
+			return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil && ok
+		},
+	}, nil
+}
+
+// Authenticate implements route.Authenticator interface.
+func (a *Basic) Authenticate(r *http.Request) (*route.Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, route.ErrNoCredentials
+	}
+	if !a.Check(username, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &route.Principal{ID: username, Scheme: "basic"}, nil
+}
+
+// Challenge implements route.Challenger interface.
+func (a *Basic) Challenge() string {
+	return `Basic realm="` + strings.Replace(a.Realm, `"`, `'`, -1) + `", charset="UTF-8"`
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoadHtpasswd(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte("# users\nalice:"+string(hash)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadHtpasswd("admin", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Check("alice", "s3cret") || a.Check("alice", "wrong") || a.Check("bob", "s3cret") {
+		t.Fatal("unexpected check result")
+	}
+
+	// Unknown users take as long as known users with wrong password
+	measure := func(user string) time.Duration {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			a.Check(user, "wrong")
+		}
+		return time.Since(start)
+	}
+	known, unknown := measure("alice"), measure("bob")
+	if unknown < known/2 {
+		t.Fatalf("unknown user check took %v, known user %v", unknown, known)
+	}
+}
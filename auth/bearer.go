@@ -0,0 +1,173 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// ErrNoToken represents request without bearer token
+var ErrNoToken = errors.New("auth: Bearer token was not found")
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	claimsKey contextKey = iota
+	errorKey
+)
+
+// JWTOptions store JWT validation configurations
+type JWTOptions struct {
+	// Keys gets the verification key of each token
+	Keys KeySource
+	// Algorithms lists accepted algorithms, defaults to all supported
+	Algorithms []string
+	// Issuer is the expected iss claim, if set
+	Issuer string
+	// Audience is the expected aud claim, if set
+	Audience string
+	// Leeway tolerates clock skew on exp and nbf validation
+	Leeway time.Duration
+	// Extract gets the token from request, defaults to bearer token
+	Extract func(*http.Request) string
+	// ErrorHandler handles rejected requests, defaults to 401 response
+	ErrorHandler http.Handler
+}
+
+// JWT is the middleware that authenticates requests with JSON Web Tokens.
+type JWT struct {
+	JWTOptions
+}
+
+// NewJWT creates JWT authentication middleware.
+func NewJWT(o JWTOptions) *JWT {
+	if len(o.Algorithms) == 0 {
+		o.Algorithms = []string{HS256, RS256, ES256, EdDSA}
+	}
+	if o.Extract == nil {
+		o.Extract = BearerToken
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = http.HandlerFunc(unauthorized)
+	}
+	return &JWT{JWTOptions: o}
+}
+
+// Verify verifies the token signature and claims, and gets its claims.
+func (m *JWT) Verify(ctx context.Context, s string) (Claims, error) {
+	t, err := parseToken(s)
+	if err != nil {
+		return nil, err
+	}
+	if !m.allowed(t.header.Alg) {
+		return nil, ErrAlgorithm
+	}
+	key, err := m.Keys.Key(ctx, t.header.Kid, t.header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(t.header.Alg, key, t.signed, t.signature); err != nil {
+		return nil, err
+	}
+	if err := validateClaims(t.claims, &m.JWTOptions, time.Now()); err != nil {
+		return nil, err
+	}
+	return t.claims, nil
+}
+
+// allowed checks whether the algorithm is accepted.
+func (m *JWT) allowed(alg string) bool {
+	for _, a := range m.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *JWT) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	s := m.Extract(r)
+	if s == "" {
+		m.fail(w, r, ErrNoToken)
+		return
+	}
+	claims, err := m.Verify(r.Context(), s)
+	if err != nil {
+		m.fail(w, r, err)
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), claimsKey, claims))
+	next.ServeHTTP(w, route.WithPrincipal(r, claims.Principal()))
+}
+
+// Authenticate implements route.Authenticator interface.
+func (m *JWT) Authenticate(r *http.Request) (*route.Principal, error) {
+	s := m.Extract(r)
+	if s == "" {
+		return nil, route.ErrNoCredentials
+	}
+	claims, err := m.Verify(r.Context(), s)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Principal(), nil
+}
+
+// Challenge implements route.Challenger interface.
+func (m *JWT) Challenge() string {
+	return "Bearer"
+}
+
+// fail stores the failure reason and runs the error handler.
+func (m *JWT) fail(w http.ResponseWriter, r *http.Request, err error) {
+	r = r.WithContext(context.WithValue(r.Context(), errorKey, err))
+	m.ErrorHandler.ServeHTTP(w, r)
+}
+
+// BearerToken gets token from the Authorization request header.
+func BearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// GetClaims gets verified token claims of the request, set by the JWT
+// middleware or by route authentication with the JWT authenticator.
+func GetClaims(r *http.Request) Claims {
+	if claims, ok := r.Context().Value(claimsKey).(Claims); ok {
+		return claims
+	}
+	if p := route.GetPrincipal(r); p != nil && p.Scheme == "bearer" {
+		return Claims(p.Attributes)
+	}
+	return nil
+}
+
+// GetError gets the reason why authentication middleware rejected the
+// request.
+func GetError(r *http.Request) error {
+	err, _ := r.Context().Value(errorKey).(error)
+	return err
+}
+
+// unauthorized is the default authentication error handler.
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	if err := GetError(r); err == ErrNoToken {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	http.Error(w, "401 Unauthorized", 401)
+}
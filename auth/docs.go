@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package auth authenticates API requests. The JWT middleware validates bearer
+tokens signed with HS256, RS256, ES256, or EdDSA, checks the exp, nbf, iss,
+and aud claims with clock skew leeway, and stores the verified claims on the
+request context.
+
+Verification keys may be static or fetched from a JWKS URL. Fetched keys are
+cached and refreshed periodically, and also when a token is signed with an
+unknown key identifier so keys can be rotated by the identity provider.
+
+	jwt := auth.NewJWT(auth.JWTOptions{
+		Keys:     auth.NewJWKS("https://id.example.com/.well-known/jwks.json"),
+		Issuer:   "https://id.example.com/",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+	})
+	router.PathPrefix("/api").Middleware(jwt).Group(func(r *route.Router) {
+		r.GetFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, auth.GetClaims(r).Subject())
+		})
+	})
+
+The package also implements route.Authenticator for HTTP Basic, API keys,
+and HMAC request signatures. Basic credentials are compared in constant
+time, or loaded from a bcrypt htpasswd file. HMAC signatures cover the
+method, request URI, timestamp, and body hash, and are rejected outside of
+the replay window or when reused. Authenticators are composable, so a route
+may accept any of several schemes.
+
+	basic, err := auth.LoadHtpasswd("admin", "/etc/app/htpasswd")
+	keys := auth.NewAPIKey(lookupKey)
+	signed := auth.NewHMAC(lookupSecret)
+	router.Authenticate(basic, keys, signed, jwt).Group(func(r *route.Router) {
+		r.GetFunc("/reports", reports)
+	})
+*/
+package auth
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,200 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// ErrMalformedSignature represents unparseable signature header
+var ErrMalformedSignature = errors.New("auth: Malformed request signature")
+
+// ErrStaleSignature represents signature outside of the replay window
+var ErrStaleSignature = errors.New("auth: Request signature is outside of replay window")
+
+// ErrReplayedSignature represents signature that was already used
+var ErrReplayedSignature = errors.New("auth: Request signature was already used")
+
+// ErrBodyTooLarge represents signed request body over the size limit
+var ErrBodyTooLarge = errors.New("auth: Signed request body is too large")
+
+// hmacScheme define Authorization scheme of signed requests
+const hmacScheme = "HMAC-SHA256"
+
+// HMAC authenticates requests signed with a shared secret. The signature
+// covers the method, the request URI, the timestamp, and the body hash, and
+// is sent in the Authorization header.
+//
+//	Authorization: HMAC-SHA256 keyId="partner", timestamp="1500000000", signature="..."
+type HMAC struct {
+	// Lookup gets the secret and principal of the key identifier, or nil
+	// secret for unknown keys
+	Lookup func(ctx context.Context, keyID string) ([]byte, *route.Principal, error)
+	// Window is the maximum clock difference of the timestamp
+	Window time.Duration
+	// MaxBody is the maximum signed body size
+	MaxBody int64
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	sweep   time.Time
+}
+
+// NewHMAC creates request signature authenticator with the lookup function.
+func NewHMAC(lookup func(ctx context.Context, keyID string) ([]byte, *route.Principal, error)) *HMAC {
+	return &HMAC{
+		Lookup:  lookup,
+		Window:  5 * time.Minute,
+		MaxBody: 10 << 20,
+	}
+}
+
+// Authenticate implements route.Authenticator interface.
+func (a *HMAC) Authenticate(r *http.Request) (*route.Principal, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, hmacScheme+" ") {
+		return nil, route.ErrNoCredentials
+	}
+	params := parseParams(h[len(hmacScheme)+1:])
+	keyID, stamp, sig := params["keyId"], params["timestamp"], params["signature"]
+	signature, err := base64.StdEncoding.Strict().DecodeString(sig)
+	if keyID == "" || err != nil {
+		return nil, ErrMalformedSignature
+	}
+	ts, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return nil, ErrMalformedSignature
+	}
+	// Enforce replay window
+	now := time.Now()
+	if d := now.Sub(time.Unix(ts, 0)); d > a.Window || d < -a.Window {
+		return nil, ErrStaleSignature
+	}
+	secret, p, err := a.Lookup(r.Context(), keyID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrInvalidCredentials
+	}
+	bodyHash, err := a.hashBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(signature, computeSignature(secret, r.Method, r.URL.RequestURI(), stamp, bodyHash)) {
+		return nil, ErrSignature
+	}
+	// Remember the MAC rather than its encoding, which is not unique
+	if !a.remember(string(signature), now) {
+		return nil, ErrReplayedSignature
+	}
+	if p == nil {
+		p = &route.Principal{ID: keyID}
+	}
+	if p.Scheme == "" {
+		p.Scheme = "hmac"
+	}
+	return p, nil
+}
+
+// Challenge implements route.Challenger interface.
+func (a *HMAC) Challenge() string {
+	return hmacScheme
+}
+
+// hashBody reads the request body, computes its hash, and restores it so
+// the handler can read it again.
+func (a *HMAC) hashBody(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return hex.EncodeToString(sha256.New().Sum(nil)), nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, a.MaxBody+1))
+	r.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > a.MaxBody {
+		return "", ErrBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// remember records the signature and reports whether it is new. Records
+// older than twice the replay window are swept periodically.
+func (a *HMAC) remember(sig string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.seen == nil {
+		a.seen = make(map[string]time.Time)
+	}
+	if now.Sub(a.sweep) > a.Window {
+		for s, t := range a.seen {
+			if now.Sub(t) > 2*a.Window {
+				delete(a.seen, s)
+			}
+		}
+		a.sweep = now
+	}
+	if _, ok := a.seen[sig]; ok {
+		return false
+	}
+	a.seen[sig] = now
+	return true
+}
+
+// SignRequest signs outgoing request with the key identifier and secret.
+// The request body is buffered to compute its hash.
+func SignRequest(r *http.Request, keyID string, secret []byte) error {
+	body := []byte{}
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	stamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := computeSignature(secret, r.Method, r.URL.RequestURI(), stamp, hex.EncodeToString(sum[:]))
+	r.Header.Set("Authorization", hmacScheme+` keyId="`+keyID+`", timestamp="`+stamp+
+		`", signature="`+base64.StdEncoding.EncodeToString(sig)+`"`)
+	return nil
+}
+
+// computeSignature computes signature of the canonical request string.
+func computeSignature(secret []byte, method, uri, stamp, bodyHash string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, method+"\n"+uri+"\n"+stamp+"\n"+bodyHash)
+	return mac.Sum(nil)
+}
+
+// parseParams parses comma separated key="value" pairs.
+func parseParams(s string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		i := strings.IndexByte(part, '=')
+		if i < 0 {
+			continue
+		}
+		params[strings.TrimSpace(part[:i])] = strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+	}
+	return params
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func newTestHMAC() *HMAC {
+	return NewHMAC(func(ctx context.Context, keyID string) ([]byte, *route.Principal, error) {
+		if keyID != "partner" {
+			return nil, nil, nil
+		}
+		return []byte("secret"), nil, nil
+	})
+}
+
+func TestHMACAuthenticate(t *testing.T) {
+	a := newTestHMAC()
+	r := httptest.NewRequest("POST", "/hooks?a=1", strings.NewReader(`{"event":"paid"}`))
+	if err := SignRequest(r, "partner", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	p, err := a.Authenticate(r)
+	if err != nil || p.ID != "partner" || p.Scheme != "hmac" {
+		t.Fatalf("got %v %v", p, err)
+	}
+	// The body is restored for the handler
+	if body, _ := io.ReadAll(r.Body); string(body) != `{"event":"paid"}` {
+		t.Fatalf("body = %q", body)
+	}
+
+	tampered := httptest.NewRequest("POST", "/hooks?a=2", strings.NewReader(`{"event":"paid"}`))
+	tampered.Header.Set("Authorization", r.Header.Get("Authorization"))
+	if _, err := a.Authenticate(tampered); err != ErrSignature {
+		t.Fatalf("tampered request: got %v, want ErrSignature", err)
+	}
+	unknown := httptest.NewRequest("GET", "/", nil)
+	SignRequest(unknown, "other", []byte("secret"))
+	if _, err := a.Authenticate(unknown); err != ErrInvalidCredentials {
+		t.Fatalf("unknown key: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestHMACReplay(t *testing.T) {
+	a := newTestHMAC()
+	r := httptest.NewRequest("GET", "/reports", nil)
+	SignRequest(r, "partner", []byte("secret"))
+	header := r.Header.Get("Authorization")
+	if _, err := a.Authenticate(r); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := httptest.NewRequest("GET", "/reports", nil)
+	replay.Header.Set("Authorization", header)
+	if _, err := a.Authenticate(replay); err != ErrReplayedSignature {
+		t.Fatalf("replay: got %v, want ErrReplayedSignature", err)
+	}
+
+	// Flip the unused trailing bits of the base64 signature, which decodes
+	// to the same MAC with lenient decoding
+	i := strings.Index(header, `signature="`) + len(`signature="`)
+	sig := header[i : len(header)-1]
+	alphabet := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	pos := len(sig) - 2
+	v := strings.IndexByte(alphabet, sig[pos])
+	for bits := 1; bits < 4; bits++ {
+		variant := sig[:pos] + string(alphabet[v^bits]) + sig[pos+1:]
+		replay := httptest.NewRequest("GET", "/reports", nil)
+		replay.Header.Set("Authorization", header[:i]+variant+`"`)
+		if _, err := a.Authenticate(replay); err == nil {
+			t.Fatalf("non-canonical signature %q was accepted", variant)
+		}
+	}
+}
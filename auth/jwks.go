@@ -0,0 +1,233 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound represents unknown token signing key
+var ErrKeyNotFound = errors.New("auth: Signing key was not found")
+
+// KeySource gets verification key of a token from its key identifier and
+// algorithm. HS256 keys are []byte, while the others are public keys.
+type KeySource interface {
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// StaticKey is a single verification key used for every token
+type StaticKey struct {
+	Value interface{}
+}
+
+// Key implements auth.KeySource interface.
+func (k StaticKey) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	return k.Value, nil
+}
+
+// JWK store a JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// PublicKey decodes the public key of RSA, EC P-256 and Ed25519 keys.
+func (k *JWK) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			break
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			break
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("auth: Invalid Ed25519 key %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("auth: Unsupported key type %s %s", k.Kty, k.Crv)
+}
+
+// decodeInt decodes base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWKS fetches and caches verification keys from a JWKS URL. Keys are
+// refetched after RefreshInterval, or when a token refers to an unknown key
+// identifier but no more often than MinRefreshInterval.
+type JWKS struct {
+	URL                string
+	Client             *http.Client
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+	mu                 sync.Mutex
+	keys               map[string]interface{}
+	fetched            time.Time
+	call               *refreshCall
+}
+
+// refreshCall store key set fetch shared by concurrent callers
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS creates key source that fetches keys from the URL.
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		URL:                url,
+		Client:             http.DefaultClient,
+		RefreshInterval:    time.Hour,
+		MinRefreshInterval: time.Minute,
+	}
+}
+
+// Key implements auth.KeySource interface.
+func (s *JWKS) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	keys, age := s.current()
+	if keys == nil || age > s.RefreshInterval {
+		if err := s.refresh(ctx); err != nil && keys == nil {
+			return nil, err
+		}
+		keys, age = s.current()
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Refetch keys for rotated signing key
+	if age > s.MinRefreshInterval {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		keys, _ = s.current()
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// current gets the cached keys and their age.
+func (s *JWKS) current() (map[string]interface{}, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys, time.Since(s.fetched)
+}
+
+// Refresh fetches the key set immediately.
+func (s *JWKS) Refresh(ctx context.Context) error {
+	return s.refresh(ctx)
+}
+
+// refresh fetches the key set and replaces the cached keys. Concurrent
+// callers wait for the same fetch, which runs without holding the lock so
+// that cached keys are served while the key set is fetched.
+func (s *JWKS) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	if c := s.call; c != nil {
+		s.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c := &refreshCall{done: make(chan struct{})}
+	s.call = c
+	s.fetched = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.call = nil
+	c.err = err
+	s.mu.Unlock()
+	close(c.done)
+	return err
+}
+
+// fetch gets the key set from the URL.
+func (s *JWKS) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("auth: JWKS request failed with status %d", res.StatusCode)
+	}
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Skip unsupported keys, including symmetric keys
+		if key, err := k.PublicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKey store signing key published on the test JWKS server
+type testKey struct {
+	kid string
+	key *ecdsa.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) *testKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{kid: kid, key: key}
+}
+
+func (k *testKey) jwk() JWK {
+	enc := base64.RawURLEncoding
+	return JWK{
+		Kty: "EC", Kid: k.kid, Use: "sig", Alg: ES256, Crv: "P-256",
+		X: enc.EncodeToString(k.key.X.FillBytes(make([]byte, 32))),
+		Y: enc.EncodeToString(k.key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func (k *testKey) sign(t *testing.T, claims Claims) string {
+	enc := base64.RawURLEncoding
+	h, _ := json.Marshal(map[string]string{"alg": ES256, "typ": "JWT", "kid": k.kid})
+	c, _ := json.Marshal(claims)
+	signed := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+	sum := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, k.key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+// testJWKS serves the current key set and counts the requests
+type testJWKS struct {
+	mu      sync.Mutex
+	keys    []*testKey
+	fetches int32
+	delay   time.Duration
+}
+
+func (s *testJWKS) set(keys ...*testKey) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+func (s *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.fetches, 1)
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	set := struct {
+		Keys []JWK `json:"keys"`
+	}{}
+	for _, k := range s.keys {
+		set.Keys = append(set.Keys, k.jwk())
+	}
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(set)
+}
+
+func TestJWKSRotation(t *testing.T) {
+	k1, k2 := newTestKey(t, "k1"), newTestKey(t, "k2")
+	idp := &testJWKS{}
+	idp.set(k1)
+	srv := httptest.NewServer(idp)
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL)
+	jwks.MinRefreshInterval = 0
+	m := NewJWT(JWTOptions{Keys: jwks, Issuer: "idp"})
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour).Unix()
+
+	claims, err := m.Verify(ctx, k1.sign(t, Claims{"sub": "alice", "iss": "idp", "exp": exp}))
+	if err != nil || claims.Subject() != "alice" {
+		t.Fatalf("k1 token: %v %v", claims, err)
+	}
+	// Rotate the signing key, unknown key identifier refetches the key set
+	idp.set(k2)
+	if _, err := m.Verify(ctx, k2.sign(t, Claims{"sub": "bob", "iss": "idp", "exp": exp})); err != nil {
+		t.Fatalf("k2 token after rotation: %v", err)
+	}
+	if _, err := m.Verify(ctx, k1.sign(t, Claims{"sub": "alice", "iss": "idp", "exp": exp})); err != ErrKeyNotFound {
+		t.Fatalf("k1 token after rotation: got %v, want ErrKeyNotFound", err)
+	}
+	if n := atomic.LoadInt32(&idp.fetches); n != 3 {
+		t.Fatalf("fetched %d times, want 3", n)
+	}
+	// Tokens signed by other keys and expired tokens are rejected
+	if _, err := m.Verify(ctx, (&testKey{kid: "k2", key: k1.key}).sign(t, Claims{"iss": "idp", "exp": exp})); err != ErrSignature {
+		t.Fatalf("forged token: got %v, want ErrSignature", err)
+	}
+	if _, err := m.Verify(ctx, k2.sign(t, Claims{"iss": "idp", "exp": time.Now().Add(-time.Hour).Unix()})); err != ErrExpired {
+		t.Fatalf("expired token: got %v, want ErrExpired", err)
+	}
+}
+
+func TestJWKSMinRefreshInterval(t *testing.T) {
+	k1 := newTestKey(t, "k1")
+	idp := &testJWKS{}
+	idp.set(k1)
+	srv := httptest.NewServer(idp)
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL)
+	for i := 0; i < 3; i++ {
+		if _, err := jwks.Key(context.Background(), "unknown", ES256); err != ErrKeyNotFound {
+			t.Fatalf("got %v, want ErrKeyNotFound", err)
+		}
+	}
+	if n := atomic.LoadInt32(&idp.fetches); n != 1 {
+		t.Fatalf("fetched %d times, want 1", n)
+	}
+}
+
+func TestJWKSConcurrentRefresh(t *testing.T) {
+	k1 := newTestKey(t, "k1")
+	idp := &testJWKS{delay: 100 * time.Millisecond}
+	idp.set(k1)
+	srv := httptest.NewServer(idp)
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL)
+	jwks.MinRefreshInterval = 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jwks.Key(context.Background(), "k1", ES256); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&idp.fetches); n != 1 {
+		t.Fatalf("fetched %d times, want 1", n)
+	}
+	// Cached keys are served while the key set is refetched
+	go jwks.Key(context.Background(), "unknown", ES256)
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	if _, err := jwks.Key(context.Background(), "k1", ES256); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("cached key blocked for %v during refresh", d)
+	}
+}
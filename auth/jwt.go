@@ -0,0 +1,239 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// ErrMalformedToken represents token that is not a valid compact JWS
+var ErrMalformedToken = errors.New("auth: Malformed token")
+
+// ErrAlgorithm represents token signed with disallowed algorithm
+var ErrAlgorithm = errors.New("auth: Token algorithm is not allowed")
+
+// ErrSignature represents token signature mismatch
+var ErrSignature = errors.New("auth: Invalid token signature")
+
+// ErrExpired represents token used after its exp claim
+var ErrExpired = errors.New("auth: Token has expired")
+
+// ErrNotYetValid represents token used before its nbf claim
+var ErrNotYetValid = errors.New("auth: Token is not valid yet")
+
+// ErrIssuer represents token issued by unexpected issuer
+var ErrIssuer = errors.New("auth: Invalid token issuer")
+
+// ErrAudience represents token issued for another audience
+var ErrAudience = errors.New("auth: Invalid token audience")
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// Claims store JSON Web Token claims set
+type Claims map[string]interface{}
+
+// GetString gets string claim by its name.
+func (c Claims) GetString(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Subject gets the sub claim.
+func (c Claims) Subject() string {
+	return c.GetString("sub")
+}
+
+// Issuer gets the iss claim.
+func (c Claims) Issuer() string {
+	return c.GetString("iss")
+}
+
+// Audience gets the aud claim, which may be a string or a string array.
+func (c Claims) Audience() []string {
+	return c.GetStrings("aud")
+}
+
+// GetStrings gets string array claim by its name. Single string claims are
+// returned as a one element array.
+func (c Claims) GetStrings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var s []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+// GetTime gets NumericDate claim by its name.
+func (c Claims) GetTime(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return time.Unix(int64(f), 0), true
+		}
+	case float64:
+		return time.Unix(int64(v), 0), true
+	}
+	return time.Time{}, false
+}
+
+// Principal gets authenticated principal from the claims. Roles are taken
+// from the roles claim, and scopes from the scope or scp claim.
+func (c Claims) Principal() *route.Principal {
+	scopes := c.GetStrings("scp")
+	if scope := c.GetString("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+	}
+	return &route.Principal{
+		ID:         c.Subject(),
+		Scheme:     "bearer",
+		Roles:      c.GetStrings("roles"),
+		Scopes:     scopes,
+		Attributes: c,
+	}
+}
+
+// header define JOSE header of a token
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// token store decoded compact JWS parts
+type token struct {
+	header    header
+	claims    Claims
+	signed    []byte
+	signature []byte
+}
+
+// parseToken decodes compact JWS without verifying it.
+func parseToken(s string) (*token, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	t := &token{signed: []byte(parts[0] + "." + parts[1])}
+	h, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := json.Unmarshal(h, &t.header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&t.claims); err != nil || t.claims == nil {
+		return nil, ErrMalformedToken
+	}
+	if t.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, ErrMalformedToken
+	}
+	return t, nil
+}
+
+// verifySignature checks token signature with the key. The key type must
+// match the algorithm to prevent algorithm confusion.
+func verifySignature(alg string, key interface{}, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrAlgorithm
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrSignature
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrAlgorithm
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrSignature
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrAlgorithm
+		}
+		if len(signature) != 64 {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrSignature
+		}
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrAlgorithm
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return ErrSignature
+		}
+	default:
+		return ErrAlgorithm
+	}
+	return nil
+}
+
+// validateClaims checks the registered time, issuer and audience claims.
+func validateClaims(c Claims, o *JWTOptions, now time.Time) error {
+	if exp, ok := c.GetTime("exp"); ok && !now.Before(exp.Add(o.Leeway)) {
+		return ErrExpired
+	}
+	if nbf, ok := c.GetTime("nbf"); ok && now.Add(o.Leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+	if o.Issuer != "" && c.Issuer() != o.Issuer {
+		return ErrIssuer
+	}
+	if o.Audience != "" {
+		for _, aud := range c.Audience() {
+			if aud == o.Audience {
+				return nil
+			}
+		}
+		return ErrAudience
+	}
+	return nil
+}
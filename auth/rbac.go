@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// RBAC is role-based access control policy. Roles are granted permissions
+// and may inherit permissions of other roles. A permission ending with .*
+// grants every permission under the prefix, while * grants everything.
+type RBAC struct {
+	mu       sync.RWMutex
+	grants   map[string][]string
+	inherits map[string][]string
+}
+
+// NewRBAC creates empty role-based access control policy.
+func NewRBAC() *RBAC {
+	return &RBAC{
+		grants:   make(map[string][]string),
+		inherits: make(map[string][]string),
+	}
+}
+
+// Grant gives permissions to the role.
+func (p *RBAC) Grant(role string, permissions ...string) *RBAC {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[role] = append(p.grants[role], permissions...)
+	return p
+}
+
+// Inherit gives the role all permissions of the parent roles. A principal
+// with the role also satisfies role requirements of the parents.
+func (p *RBAC) Inherit(role string, parents ...string) *RBAC {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inherits[role] = append(p.inherits[role], parents...)
+	return p
+}
+
+// Roles gets the roles with all inherited roles.
+func (p *RBAC) Roles(roles ...string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := make(map[string]bool)
+	var all []string
+	for len(roles) > 0 {
+		role := roles[0]
+		roles = roles[1:]
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		all = append(all, role)
+		roles = append(roles, p.inherits[role]...)
+	}
+	return all
+}
+
+// Can checks whether any of the roles is granted the permission.
+func (p *RBAC) Can(roles []string, permission string) bool {
+	all := p.Roles(roles...)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, role := range all {
+		for _, grant := range p.grants[role] {
+			if grant == permission || grant == "*" ||
+				strings.HasSuffix(grant, ".*") && strings.HasPrefix(permission, grant[:len(grant)-1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorize implements route.Policy interface.
+func (p *RBAC) Authorize(r *http.Request, principal *route.Principal, req route.Requirement) bool {
+	if len(req.Roles) > 0 && !route.HasAny(p.Roles(principal.Roles...), req.Roles) {
+		return false
+	}
+	if !route.HasAll(principal.Scopes, req.Scopes) {
+		return false
+	}
+	for _, perm := range req.Permissions {
+		if !p.Can(principal.Roles, perm) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNoKeys represents codec created without any key
+var ErrNoKeys = errors.New("browser: Codec requires at least one key")
+
+// ErrInvalidValue represents tampered or malformed cookie value
+var ErrInvalidValue = errors.New("browser: Invalid cookie value")
+
+// ErrExpiredValue represents cookie value older than codec maximum age
+var ErrExpiredValue = errors.New("browser: Expired cookie value")
+
+// Codec signs or encrypts cookie values with rotating keys. The first key
+// is used to encode values, while all keys are tried to decode values.
+type Codec struct {
+	// MaxAge rejects values encoded earlier than the duration, if set
+	MaxAge time.Duration
+	keys   [][]byte
+	aeads  []cipher.AEAD
+}
+
+// NewSigner creates codec that signs values with HMAC-SHA256. Signed
+// values are readable by the client but can not be tampered.
+func NewSigner(keys ...[]byte) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return &Codec{keys: keys}, nil
+}
+
+// NewEncrypter creates codec that encrypts values with AES-GCM. Each key
+// must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+func NewEncrypter(keys ...[]byte) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	c := &Codec{keys: keys}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+	return c, nil
+}
+
+// Encode signs or encrypts the value bound to the cookie name.
+func (c *Codec) Encode(name string, value []byte) (string, error) {
+	// Prepend timestamp to the value
+	payload := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	payload = append(payload, value...)
+	if c.aeads != nil {
+		nonce := make([]byte, c.aeads[0].NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		sealed := c.aeads[0].Seal(nonce, nonce, payload, []byte(name))
+		return base64.RawURLEncoding.EncodeToString(sealed), nil
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(c.keys[0], name, payload)), nil
+}
+
+// Decode verifies or decrypts the value bound to the cookie name.
+func (c *Codec) Decode(name, value string) ([]byte, error) {
+	var payload []byte
+	if c.aeads != nil {
+		payload = c.open(name, value)
+	} else {
+		payload = c.verify(name, value)
+	}
+	if len(payload) < 8 {
+		return nil, ErrInvalidValue
+	}
+	// Check timestamp against maximum age
+	if c.MaxAge > 0 {
+		stamp := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+		if time.Since(stamp) > c.MaxAge {
+			return nil, ErrExpiredValue
+		}
+	}
+	return payload[8:], nil
+}
+
+// sign computes signature of the payload bound to the cookie name.
+func (c *Codec) sign(key []byte, name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// verify gets signed payload if signature matches any key.
+func (c *Codec) verify(name, value string) []byte {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil {
+		return nil
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil {
+		return nil
+	}
+	for _, key := range c.keys {
+		if hmac.Equal(sig, c.sign(key, name, payload)) {
+			return payload
+		}
+	}
+	return nil
+}
+
+// open gets decrypted payload if any key can decrypt it.
+func (c *Codec) open(name, value string) []byte {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	for _, aead := range c.aeads {
+		n := aead.NonceSize()
+		if len(sealed) < n {
+			return nil
+		}
+		payload, err := aead.Open(nil, sealed[:n], sealed[n:], []byte(name))
+		if err == nil {
+			return payload
+		}
+	}
+	return nil
+}
+
+// SetCookie encodes the cookie value and adds it to the response headers.
+func (c *Codec) SetCookie(w http.ResponseWriter, cookie *http.Cookie) error {
+	value, err := c.Encode(cookie.Name, []byte(cookie.Value))
+	if err != nil {
+		return err
+	}
+	encoded := *cookie
+	encoded.Value = value
+	http.SetCookie(w, &encoded)
+	return nil
+}
+
+// Cookie gets decoded value of the named request cookie.
+func (c *Codec) Cookie(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := c.Decode(name, cookie.Value)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/mandala/omnibus/route"
+)
+
+// ErrCSRFToken represents missing or mismatched CSRF token
+var ErrCSRFToken = errors.New("browser: Invalid CSRF token")
+
+// ErrCSRFOrigin represents cross-origin unsafe request
+var ErrCSRFOrigin = errors.New("browser: Cross-origin request was rejected")
+
+// ErrNoSession represents session middleware missing before CSRF middleware
+var ErrNoSession = errors.New("browser: Session middleware is required")
+
+// csrfSessionKey define session key of the synchronizer token
+const csrfSessionKey = "_csrf"
+
+// csrfTokenLength define the number of random bytes of a token
+const csrfTokenLength = 32
+
+// CSRFOptions store CSRF protection related configurations
+type CSRFOptions struct {
+	// FieldName is the form field of the submitted token
+	FieldName string
+	// HeaderName is the request header of the submitted token
+	HeaderName string
+	// DoubleSubmit stores the token in a cookie instead of the session, for
+	// applications without session middleware
+	DoubleSubmit bool
+	// Cookie configures the double submit token cookie
+	Cookie Options
+	// Codec signs the double submit token cookie, if set
+	Codec *Codec
+	// TrustedOrigins lists other origins allowed to submit requests, such
+	// as https://admin.example.com
+	TrustedOrigins []string
+	// ErrorHandler handles rejected requests, defaults to 403 response
+	ErrorHandler http.Handler
+}
+
+// csrfState store request CSRF token for template functions
+type csrfState struct {
+	token []byte
+	field string
+}
+
+// CSRF is the middleware that protects unsafe requests from cross-site
+// request forgery. It supports synchronizer tokens stored on the session
+// and double submit cookie tokens for stateless applications.
+type CSRF struct {
+	CSRFOptions
+	mu     sync.RWMutex
+	exempt map[*mux.Route]bool
+}
+
+// NewCSRF creates CSRF protection middleware.
+func NewCSRF(o CSRFOptions) *CSRF {
+	if o.FieldName == "" {
+		o.FieldName = "csrf_token"
+	}
+	if o.HeaderName == "" {
+		o.HeaderName = "X-CSRF-Token"
+	}
+	if o.Cookie.Name == "" {
+		o.Cookie.Name = "csrf"
+	}
+	if o.Cookie.Path == "" {
+		o.Cookie.Path = "/"
+	}
+	if o.Cookie.SameSite == 0 {
+		o.Cookie.SameSite = http.SameSiteLaxMode
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = http.HandlerFunc(csrfError)
+	}
+	return &CSRF{
+		CSRFOptions: o,
+		exempt:      make(map[*mux.Route]bool),
+	}
+}
+
+// Exempt skips the protection on routes, such as webhook endpoints that
+// are authenticated in other ways.
+func (m *CSRF) Exempt(routes ...*route.Route) *CSRF {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rt := range routes {
+		m.exempt[rt.Route] = true
+	}
+	return m
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *CSRF) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	token, err := m.token(w, r)
+	if err != nil {
+		m.fail(w, r, err)
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), csrfKey, &csrfState{
+		token: token,
+		field: m.FieldName,
+	}))
+	// Skip validation for safe methods and exempted routes
+	if isSafeMethod(r.Method) || m.isExempt(r) {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if err := m.checkOrigin(r); err != nil {
+		m.fail(w, r, err)
+		return
+	}
+	submitted := r.Header.Get(m.HeaderName)
+	if submitted == "" {
+		submitted = r.PostFormValue(m.FieldName)
+	}
+	if !validToken(token, submitted) {
+		m.fail(w, r, ErrCSRFToken)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// token gets the real token from the session or the cookie, creating a
+// new one if none exists yet.
+func (m *CSRF) token(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if !m.DoubleSubmit {
+		s := GetSession(r)
+		if s == nil {
+			return nil, ErrNoSession
+		}
+		if token, ok := s.Get(csrfSessionKey).([]byte); ok && len(token) == csrfTokenLength {
+			return token, nil
+		}
+		token := newToken()
+		s.Set(csrfSessionKey, token)
+		return token, nil
+	}
+	// Read double submit token from the cookie
+	if value, err := m.readCookie(r); err == nil {
+		if token, err := base64.RawURLEncoding.DecodeString(value); err == nil && len(token) == csrfTokenLength {
+			return token, nil
+		}
+	}
+	token := newToken()
+	cookie := &http.Cookie{
+		Name:     m.Cookie.Name,
+		Value:    base64.RawURLEncoding.EncodeToString(token),
+		Path:     m.Cookie.Path,
+		Domain:   m.Cookie.Domain,
+		MaxAge:   int(m.Cookie.MaxAge.Seconds()),
+		Secure:   m.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: m.Cookie.SameSite,
+	}
+	if m.Codec != nil {
+		return token, m.Codec.SetCookie(w, cookie)
+	}
+	http.SetCookie(w, cookie)
+	return token, nil
+}
+
+// readCookie gets double submit token cookie value.
+func (m *CSRF) readCookie(r *http.Request) (string, error) {
+	if m.Codec != nil {
+		return m.Codec.Cookie(r, m.Cookie.Name)
+	}
+	cookie, err := r.Cookie(m.Cookie.Name)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// isExempt checks whether the matched route is exempted.
+func (m *CSRF) isExempt(r *http.Request) bool {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.exempt[current]
+}
+
+// checkOrigin rejects requests with Origin or Referer header from another
+// origin. HTTPS requests without both headers are rejected as well.
+func (m *CSRF) checkOrigin(r *http.Request) error {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	scheme := route.RequestScheme(r)
+	if source == "" {
+		if scheme == "https" {
+			return ErrCSRFOrigin
+		}
+		return nil
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return ErrCSRFOrigin
+	}
+	if u.Scheme == scheme && u.Host == r.Host {
+		return nil
+	}
+	for _, origin := range m.TrustedOrigins {
+		if t, err := url.Parse(origin); err == nil && t.Scheme == u.Scheme && t.Host == u.Host {
+			return nil
+		}
+	}
+	return ErrCSRFOrigin
+}
+
+// fail stores the failure reason and runs the error handler.
+func (m *CSRF) fail(w http.ResponseWriter, r *http.Request, err error) {
+	r = r.WithContext(context.WithValue(r.Context(), csrfErrorKey, err))
+	m.ErrorHandler.ServeHTTP(w, r)
+}
+
+// CSRFToken gets masked CSRF token of the request to be submitted with
+// forms or request headers. The token is masked differently on each call
+// to mitigate BREACH attacks.
+func CSRFToken(r *http.Request) string {
+	state, ok := r.Context().Value(csrfKey).(*csrfState)
+	if !ok {
+		return ""
+	}
+	return maskToken(state.token)
+}
+
+// CSRFFailure gets the reason why CSRF middleware rejected the request.
+func CSRFFailure(r *http.Request) error {
+	err, _ := r.Context().Value(csrfErrorKey).(error)
+	return err
+}
+
+// CSRFField gets hidden form input that holds the CSRF token.
+func CSRFField(r *http.Request) template.HTML {
+	state, ok := r.Context().Value(csrfKey).(*csrfState)
+	if !ok {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(state.field) +
+		`" value="` + maskToken(state.token) + `">`)
+}
+
+// csrfError is the default CSRF error handler.
+func csrfError(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "403 Forbidden - "+CSRFFailure(r).Error(), 403)
+}
+
+// isSafeMethod checks whether the method does not change server state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+// newToken generates random CSRF token.
+func newToken() []byte {
+	b := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// maskToken encodes token XORed with random one-time pad.
+func maskToken(token []byte) string {
+	pad := newToken()
+	masked := make([]byte, 2*csrfTokenLength)
+	copy(masked, pad)
+	for i := range token {
+		masked[csrfTokenLength+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validToken compares the real token with the submitted masked token.
+func validToken(token []byte, submitted string) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil || len(masked) != 2*csrfTokenLength {
+		return false
+	}
+	unmasked := make([]byte, csrfTokenLength)
+	for i := range unmasked {
+		unmasked[i] = masked[i] ^ masked[csrfTokenLength+i]
+	}
+	return subtle.ConstantTimeCompare(token, unmasked) == 1
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func TestCSRFOrigin(t *testing.T) {
+	var token string
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{NewCSRF(CSRFOptions{
+			DoubleSubmit:   true,
+			TrustedOrigins: []string{"https://admin.example.com"},
+		})},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token = CSRFToken(r)
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "https://example.com/", nil))
+	cookies := w.Result().Cookies()
+	if w.Code != 200 || len(cookies) != 1 || token == "" {
+		t.Fatalf("GET got %d with %d cookies", w.Code, len(cookies))
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		origin  string
+		referer string
+		status  int
+	}{
+		{"same origin", "https://example.com/", "https://example.com", "", 200},
+		{"same origin referer", "https://example.com/", "", "https://example.com/form", 200},
+		{"trusted origin", "https://example.com/", "https://admin.example.com", "", 200},
+		{"cross origin", "https://example.com/", "https://evil.com", "", 403},
+		{"cross scheme", "https://example.com/", "http://example.com", "", 403},
+		{"cross origin referer", "https://example.com/", "", "https://evil.com/", 403},
+		{"https without origin", "https://example.com/", "", "", 403},
+		{"http without origin", "http://example.com/", "", "", 200},
+		{"opaque origin", "https://example.com/", "null", "", 403},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", tt.url, nil)
+		r.AddCookie(cookies[0])
+		r.Header.Set("X-CSRF-Token", token)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.referer != "" {
+			r.Header.Set("Referer", tt.referer)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	h := route.MiddlewareRunner{
+		Stack:   []route.Middleware{NewCSRF(CSRFOptions{DoubleSubmit: true})},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/", nil))
+	cookie := w.Result().Cookies()[0]
+	for _, submitted := range []string{"", "invalid"} {
+		r := httptest.NewRequest("POST", "http://example.com/", nil)
+		r.AddCookie(cookie)
+		r.Header.Set("Origin", "http://example.com")
+		r.Header.Set("X-CSRF-Token", submitted)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 403 {
+			t.Errorf("token %q: got %d, want 403", submitted, w.Code)
+		}
+	}
+}
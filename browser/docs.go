@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package browser handles browser state such as signed and encrypted cookies
+and cookie-backed sessions. Cookie values are protected with HMAC-SHA256
+signature or AES-GCM encryption, and every codec accepts multiple keys so
+the keys can be rotated without logging out users. The first key encodes new
+values while all keys are tried when decoding.
+
+	codec, err := browser.NewEncrypter(newKey, oldKey)
+
+Sessions are loaded and saved automatically by the session middleware, with
+the session data persisted on a pluggable Store. The package provides a
+cookie store, an in-memory store, and a file system store. Cookie sessions
+are limited to 4000 bytes once encoded. Sessions that cannot be saved are
+answered by the ErrorHandler instead of the handler response, so that users
+do not lose session data silently.
+
+	sessions := browser.NewSessions(browser.NewMemoryStore(), browser.Options{
+		MaxAge: 24 * time.Hour,
+		Secure: true,
+	})
+	router.Middleware(sessions).Group(func(r *route.Router) {
+		r.GetFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			s := browser.GetSession(r)
+			s.Set("visits", s.GetInt("visits")+1)
+		})
+	})
+
+Flash messages are one-time messages stored on the session, typically added
+before redirecting after a form post and consumed when the next page renders.
+Register RequestFuncMap as view request functions to read them from
+templates.
+
+	browser.AddFlash(r, browser.LevelSuccess, "Profile saved")
+	http.Redirect(w, r, "/profile", 303)
+
+The CSRF middleware validates a token on every unsafe request, submitted as
+a form field or a request header, and checks the Origin or Referer header.
+Tokens are stored on the session, or on a separate cookie when DoubleSubmit
+is enabled for applications without sessions. Webhook routes that are
+authenticated in other ways may be exempted.
+
+	csrf := browser.NewCSRF(browser.CSRFOptions{})
+	router.Middleware(sessions, csrf).Group(func(r *route.Router) {
+		csrf.Exempt(r.Post("/webhook", webhook))
+	})
+*/
+package browser
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"encoding/gob"
+	"net/http"
+)
+
+// flashKey define session key of the pending flash messages
+const flashKey = "_flash"
+
+// Level define severity of a flash message
+type Level int
+
+// Flash message severity levels
+const (
+	LevelInfo Level = iota
+	LevelSuccess
+	LevelWarning
+	LevelError
+)
+
+// String gets lowercase level name, suitable as CSS class name.
+func (l Level) String() string {
+	switch l {
+	case LevelSuccess:
+		return "success"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return "info"
+}
+
+// Flash store one-time message shown on the next rendered page
+type Flash struct {
+	Level   Level
+	Message string
+}
+
+func init() {
+	// Register flash type for session serialization
+	gob.Register([]Flash{})
+}
+
+// AddFlash adds flash message to the session.
+func (s *Session) AddFlash(level Level, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, _ := s.values[flashKey].([]Flash)
+	s.values[flashKey] = append(flashes, Flash{Level: level, Message: message})
+	s.changed = true
+}
+
+// Flashes gets and removes all flash messages from the session.
+func (s *Session) Flashes() []Flash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, ok := s.values[flashKey].([]Flash)
+	if !ok {
+		return nil
+	}
+	delete(s.values, flashKey)
+	s.changed = true
+	return flashes
+}
+
+// AddFlash adds flash message to the request session. It does nothing when
+// the request has no session.
+func AddFlash(r *http.Request, level Level, message string) {
+	if s := GetSession(r); s != nil {
+		s.AddFlash(level, message)
+	}
+}
+
+// Flashes gets and removes all flash messages from the request session.
+func Flashes(r *http.Request) []Flash {
+	if s := GetSession(r); s != nil {
+		return s.Flashes()
+	}
+	return nil
+}
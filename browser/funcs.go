@@ -0,0 +1,37 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// RequestFuncMap gets template functions bound to the request, to be
+// registered as view request functions. The flashes function gets and
+// removes the flash messages of the request session, while csrfToken and
+// csrfField get the CSRF token and its hidden form input.
+//
+//	{{range flashes}}<p class="{{.Level}}">{{.Message}}</p>{{end}}
+//	<form method="post">{{csrfField}}</form>
+func RequestFuncMap() map[string]func(*http.Request) interface{} {
+	return map[string]func(*http.Request) interface{}{
+		"flashes": func(r *http.Request) interface{} {
+			return func() []Flash {
+				return Flashes(r)
+			}
+		},
+		"csrfToken": func(r *http.Request) interface{} {
+			return func() string {
+				return CSRFToken(r)
+			}
+		},
+		"csrfField": func(r *http.Request) interface{} {
+			return func() template.HTML {
+				return CSRFField(r)
+			}
+		},
+	}
+}
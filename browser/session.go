@@ -0,0 +1,247 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	csrfKey
+	csrfErrorKey
+	sessionErrorKey
+)
+
+// Options store session cookie related configurations
+type Options struct {
+	Name     string
+	Path     string
+	Domain   string
+	MaxAge   time.Duration
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// Session store values of a browser session
+type Session struct {
+	id        string
+	oldID     string
+	values    map[string]interface{}
+	mu        sync.Mutex
+	changed   bool
+	destroyed bool
+}
+
+// ID gets the session identifier. New sessions get their identifier when
+// saved for the first time.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get gets session value by its key.
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// GetString gets session string value by its key.
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key).(string)
+	return v
+}
+
+// GetInt gets session integer value by its key.
+func (s *Session) GetInt(key string) int {
+	v, _ := s.Get(key).(int)
+	return v
+}
+
+// Set sets session value. The value type must be registered with
+// gob.Register unless it is a basic type.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes session value by its key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+// Regenerate assigns new identifier to the session while keeping its
+// values. Call Regenerate when the user privilege changes, such as after
+// login, to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = ""
+	s.changed = true
+}
+
+// Destroy removes all session values and expires the session cookie.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+	s.changed = true
+}
+
+// Sessions is the middleware that loads and saves session on each request.
+type Sessions struct {
+	Options
+	Store Store
+	// ErrorHandler responds instead of the handler when the session cannot
+	// be saved, such as cookie sessions above 4000 bytes, defaults to 500
+	// response
+	ErrorHandler http.Handler
+}
+
+// NewSessions creates session middleware with the store.
+func NewSessions(store Store, o Options) *Sessions {
+	if o.Name == "" {
+		o.Name = "session"
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	if o.SameSite == 0 {
+		o.SameSite = http.SameSiteLaxMode
+	}
+	return &Sessions{
+		Options:      o,
+		Store:        store,
+		ErrorHandler: http.HandlerFunc(sessionError),
+	}
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Sessions) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	s := m.load(r)
+	r = r.WithContext(context.WithValue(r.Context(), sessionKey, s))
+	// Save session right before the response headers are written
+	sw := &responseWriter{ResponseWriter: w}
+	sw.before = func() {
+		if err := m.save(sw.ResponseWriter, s); err != nil {
+			// Discard the handler response, the user would lose the
+			// session data silently otherwise
+			sw.err = err
+			m.ErrorHandler.ServeHTTP(sw.ResponseWriter, r.WithContext(
+				context.WithValue(r.Context(), sessionErrorKey, err)))
+		}
+	}
+	next.ServeHTTP(sw, r)
+	sw.flushHeader()
+}
+
+// load gets session from the request cookie, or creates a new one.
+func (m *Sessions) load(r *http.Request) *Session {
+	s := &Session{values: make(map[string]interface{})}
+	cookie, err := r.Cookie(m.Name)
+	if err != nil {
+		return s
+	}
+	id, values, err := m.Store.Load(cookie.Value)
+	if err != nil || values == nil {
+		return s
+	}
+	s.id = id
+	s.values = values
+	return s
+}
+
+// save persists changed session and writes the session cookie.
+func (m *Sessions) save(w http.ResponseWriter, s *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.changed {
+		return nil
+	}
+	s.changed = false
+	// Remove old session data from the store
+	if s.oldID != "" {
+		m.Store.Delete(s.oldID)
+		s.oldID = ""
+	}
+	if s.destroyed {
+		if s.id != "" {
+			m.Store.Delete(s.id)
+		}
+		m.setCookie(w, "", -1)
+		return nil
+	}
+	if s.id == "" {
+		s.id = newID()
+	}
+	token, err := m.Store.Save(s.id, s.values, m.MaxAge)
+	if err != nil {
+		return err
+	}
+	m.setCookie(w, token, int(m.MaxAge/time.Second))
+	return nil
+}
+
+// setCookie writes the session cookie to the response headers.
+func (m *Sessions) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.Name,
+		Value:    value,
+		Path:     m.Path,
+		Domain:   m.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.Secure,
+		HttpOnly: true,
+		SameSite: m.SameSite,
+	})
+}
+
+// GetSession gets session of the request loaded by session middleware.
+func GetSession(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey).(*Session)
+	return s
+}
+
+// SessionFailure gets the reason why the session middleware could not save
+// the session.
+func SessionFailure(r *http.Request) error {
+	err, _ := r.Context().Value(sessionErrorKey).(error)
+	return err
+}
+
+// sessionError is the default session save error handler.
+func sessionError(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "500 Internal Server Error", 500)
+}
+
+// newID generates random session identifier.
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func newTestSessions(t *testing.T) *Sessions {
+	codec, err := NewEncrypter(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSessions(NewCookieStore(codec), Options{})
+}
+
+func TestSessionsSave(t *testing.T) {
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{newTestSessions(t)},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GetSession(r).Set("user", "alice")
+			w.Write([]byte("ok"))
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Set-Cookie"), "session=") {
+		t.Fatalf("session cookie not set: %q", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestSessionsSaveFailure(t *testing.T) {
+	var failure error
+	m := newTestSessions(t)
+	m.ErrorHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failure = SessionFailure(r)
+		http.Error(w, "session failed", 500)
+	})
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{m},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GetSession(r).Set("token", strings.Repeat("x", 5000))
+			w.WriteHeader(200)
+			if _, err := w.Write([]byte("ok")); err == nil {
+				t.Error("write succeeded after session save failure")
+			}
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if failure != ErrCookieTooLong {
+		t.Fatalf("failure = %v, want ErrCookieTooLong", failure)
+	}
+	if w.Code != 500 || strings.Contains(w.Body.String(), "ok") {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Set-Cookie") != "" {
+		t.Fatal("session cookie set after save failure")
+	}
+}
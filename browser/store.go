@@ -0,0 +1,278 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound represents unknown or expired session on the store
+var ErrSessionNotFound = errors.New("browser: Session was not found")
+
+// ErrCookieTooLong represents session data that does not fit in a cookie
+var ErrCookieTooLong = errors.New("browser: Session cookie is too long")
+
+// Store persists session values. The token returned by Save is written to
+// the session cookie and passed back to Load on the next request.
+type Store interface {
+	Load(token string) (id string, values map[string]interface{}, err error)
+	Save(id string, values map[string]interface{}, maxAge time.Duration) (token string, err error)
+	Delete(id string) error
+}
+
+// record define serialized session data with its expiry time
+type record struct {
+	ID      string
+	Values  map[string]interface{}
+	Expires time.Time
+}
+
+// expired checks whether the record already expired.
+func (rec *record) expired() bool {
+	return !rec.Expires.IsZero() && time.Now().After(rec.Expires)
+}
+
+// encodeRecord serializes session data with gob.
+func encodeRecord(id string, values map[string]interface{}, maxAge time.Duration) ([]byte, error) {
+	rec := record{ID: id, Values: values}
+	if maxAge > 0 {
+		rec.Expires = time.Now().Add(maxAge)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRecord deserializes session data and checks its expiry.
+func decodeRecord(b []byte) (*record, error) {
+	rec := &record{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(rec); err != nil {
+		return nil, err
+	}
+	if rec.expired() {
+		return nil, ErrSessionNotFound
+	}
+	if rec.Values == nil {
+		rec.Values = make(map[string]interface{})
+	}
+	return rec, nil
+}
+
+// CookieStore keeps whole session data on the client in a protected cookie.
+// Encoded sessions are limited to 4000 bytes to fit browser cookie limits,
+// keep large values such as OIDC tokens on a server-side store.
+type CookieStore struct {
+	Codec *Codec
+	// Name binds the encoded value to the session cookie name
+	Name string
+}
+
+// NewCookieStore creates cookie store with the codec. Use an encrypting
+// codec unless the session data may be read by the client.
+func NewCookieStore(codec *Codec) *CookieStore {
+	return &CookieStore{Codec: codec, Name: "session"}
+}
+
+// Load implements browser.Store interface.
+func (s *CookieStore) Load(token string) (string, map[string]interface{}, error) {
+	b, err := s.Codec.Decode(s.Name, token)
+	if err != nil {
+		return "", nil, err
+	}
+	rec, err := decodeRecord(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return rec.ID, rec.Values, nil
+}
+
+// Save implements browser.Store interface.
+func (s *CookieStore) Save(id string, values map[string]interface{}, maxAge time.Duration) (string, error) {
+	b, err := encodeRecord(id, values, maxAge)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.Codec.Encode(s.Name, b)
+	if err != nil {
+		return "", err
+	}
+	if len(token) > 4000 {
+		return "", ErrCookieTooLong
+	}
+	return token, nil
+}
+
+// Delete implements browser.Store interface. Cookie sessions are removed
+// by expiring the cookie, so Delete does nothing.
+func (s *CookieStore) Delete(id string) error {
+	return nil
+}
+
+// MemoryStore keeps session data in the process memory.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string][]byte
+}
+
+// NewMemoryStore creates empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string][]byte)}
+}
+
+// Load implements browser.Store interface.
+func (s *MemoryStore) Load(token string) (string, map[string]interface{}, error) {
+	s.mu.RLock()
+	b, ok := s.records[token]
+	s.mu.RUnlock()
+	if !ok {
+		return "", nil, ErrSessionNotFound
+	}
+	rec, err := decodeRecord(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return rec.ID, rec.Values, nil
+}
+
+// Save implements browser.Store interface.
+func (s *MemoryStore) Save(id string, values map[string]interface{}, maxAge time.Duration) (string, error) {
+	// Store serialized values so they can not be modified after saving
+	b, err := encodeRecord(id, values, maxAge)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.records[id] = b
+	s.mu.Unlock()
+	return id, nil
+}
+
+// Delete implements browser.Store interface.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.records, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Cleanup removes expired sessions from the memory store.
+func (s *MemoryStore) Cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, b := range s.records {
+		if _, err := decodeRecord(b); err != nil {
+			delete(s.records, id)
+		}
+	}
+}
+
+// FileStore keeps session data as files in a directory.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates file store on the directory.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// path gets session file path, rejecting identifiers that are not created
+// by the session middleware.
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", ErrSessionNotFound
+	}
+	return filepath.Join(s.Dir, "session_"+id), nil
+}
+
+// Load implements browser.Store interface.
+func (s *FileStore) Load(token string) (string, map[string]interface{}, error) {
+	p, err := s.path(token)
+	if err != nil {
+		return "", nil, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", nil, ErrSessionNotFound
+	}
+	rec, err := decodeRecord(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return rec.ID, rec.Values, nil
+}
+
+// Save implements browser.Store interface.
+func (s *FileStore) Save(id string, values map[string]interface{}, maxAge time.Duration) (string, error) {
+	p, err := s.path(id)
+	if err != nil {
+		return "", err
+	}
+	b, err := encodeRecord(id, values, maxAge)
+	if err != nil {
+		return "", err
+	}
+	// Write to temporary file and rename to replace the file atomically
+	f, err := os.CreateTemp(s.Dir, "tmp_")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return id, nil
+}
+
+// Delete implements browser.Store interface.
+func (s *FileStore) Delete(id string) error {
+	p, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Cleanup removes expired session files from the directory.
+func (s *FileStore) Cleanup() error {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "session_*"))
+	if err != nil {
+		return err
+	}
+	for _, p := range files {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if _, err := decodeRecord(b); err != nil {
+			os.Remove(p)
+		}
+	}
+	return nil
+}
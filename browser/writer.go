@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package browser
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter runs a hook right before the response headers are written.
+// Once the hook fails, the response is discarded.
+type responseWriter struct {
+	http.ResponseWriter
+	before func()
+	done   bool
+	err    error
+}
+
+// flushHeader runs the hook if it has not been run yet.
+func (w *responseWriter) flushHeader() {
+	if !w.done {
+		w.done = true
+		w.before()
+	}
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *responseWriter) WriteHeader(status int) {
+	w.flushHeader()
+	if w.err != nil {
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.flushHeader()
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface.
+func (w *responseWriter) Flush() {
+	w.flushHeader()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.flushHeader()
+	if w.err != nil {
+		return nil, nil, w.err
+	}
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap gets the underlying response writer for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
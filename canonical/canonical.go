@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package canonical
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// Slash define trailing slash normalization.
+type Slash int
+
+const (
+	// SlashIgnore keeps trailing slash as requested
+	SlashIgnore Slash = iota
+	// SlashRemove removes trailing slash
+	SlashRemove
+	// SlashAdd adds trailing slash, except to paths whose last segment
+	// looks like a file name
+	SlashAdd
+)
+
+// Options store canonical URL configurations
+type Options struct {
+	// Host is the canonical host with optional port
+	Host string
+	// HTTPS redirects plain HTTP requests to HTTPS
+	HTTPS         bool
+	TrailingSlash Slash
+	// CleanPath collapses duplicate slashes and dot segments
+	CleanPath bool
+	// Lowercase lowercases the path
+	Lowercase bool
+	// Temporary uses 302 and 307 instead of 301 and 308
+	Temporary bool
+	// Skip excludes requests such as health checks and ACME challenges
+	Skip func(*http.Request) bool
+}
+
+// Canonical is the middleware that redirects requests to canonical URL.
+type Canonical struct {
+	Options
+}
+
+// New creates canonical redirect middleware.
+func New(o Options) *Canonical {
+	return &Canonical{Options: o}
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Canonical) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if m.Skip != nil && m.Skip(r) {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if target, ok := m.Target(r); ok {
+		w.Header().Set("Location", target)
+		w.WriteHeader(m.status(r))
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// Target gets canonical URL of the request, it returns false if the request
+// URL already canonical.
+func (m *Canonical) Target(r *http.Request) (string, bool) {
+	scheme := route.RequestScheme(r)
+	host := r.Host
+	changed := false
+	if m.HTTPS && scheme != "https" {
+		scheme, changed = "https", true
+		// The plain HTTP port does not serve HTTPS
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	if m.Host != "" && !strings.EqualFold(host, m.Host) {
+		host, changed = m.Host, true
+	}
+	p := r.URL.EscapedPath()
+	if np := m.normalize(p); np != p {
+		p, changed = np, true
+	}
+	if !changed {
+		return "", false
+	}
+	target := scheme + "://" + host + p
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	return target, true
+}
+
+// normalize gets canonical form of the escaped path.
+func (m *Canonical) normalize(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if m.CleanPath {
+		trailing := strings.HasSuffix(p, "/")
+		p = path.Clean(p)
+		if trailing && p != "/" {
+			p += "/"
+		}
+	}
+	if m.Lowercase {
+		p = strings.ToLower(p)
+	}
+	switch m.TrailingSlash {
+	case SlashRemove:
+		if len(p) > 1 {
+			p = strings.TrimRight(p, "/")
+			if p == "" {
+				p = "/"
+			}
+		}
+	case SlashAdd:
+		if !strings.HasSuffix(p, "/") && !strings.Contains(path.Base(p), ".") {
+			p += "/"
+		}
+	}
+	return p
+}
+
+// status gets redirect status preserving request method and body.
+func (m *Canonical) status(r *http.Request) int {
+	get := r.Method == "GET" || r.Method == "HEAD"
+	switch {
+	case get && m.Temporary:
+		return 302
+	case get:
+		return 301
+	case m.Temporary:
+		return 307
+	default:
+		return 308
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package canonical
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		method   string
+		url      string
+		status   int
+		location string
+	}{
+		{"canonical", Options{Host: "example.com", HTTPS: true}, "GET", "https://example.com/a", 200, ""},
+		{"host", Options{Host: "example.com"}, "GET", "http://www.example.com/a?b=c", 301, "http://example.com/a?b=c"},
+		{"host case", Options{Host: "example.com"}, "GET", "http://EXAMPLE.com/a", 200, ""},
+		{"https", Options{HTTPS: true}, "GET", "http://example.com/a", 301, "https://example.com/a"},
+		{"https removes port", Options{HTTPS: true}, "GET", "http://example.com:8080/a", 301, "https://example.com/a"},
+		{"https with host port", Options{HTTPS: true, Host: "example.com:8443"}, "GET", "http://example.com:8080/a", 301, "https://example.com:8443/a"},
+		{"https head", Options{HTTPS: true}, "HEAD", "http://example.com/a", 301, "https://example.com/a"},
+		{"https post", Options{HTTPS: true}, "POST", "http://example.com/a", 308, "https://example.com/a"},
+		{"temporary", Options{HTTPS: true, Temporary: true}, "GET", "http://example.com/a", 302, "https://example.com/a"},
+		{"temporary post", Options{HTTPS: true, Temporary: true}, "PUT", "http://example.com/a", 307, "https://example.com/a"},
+		{"remove slash", Options{TrailingSlash: SlashRemove}, "GET", "http://example.com/a//", 301, "http://example.com/a"},
+		{"remove slash root", Options{TrailingSlash: SlashRemove}, "GET", "http://example.com/", 200, ""},
+		{"add slash", Options{TrailingSlash: SlashAdd}, "GET", "http://example.com/a", 301, "http://example.com/a/"},
+		{"add slash file", Options{TrailingSlash: SlashAdd}, "GET", "http://example.com/a.css", 200, ""},
+		{"ignore slash", Options{}, "GET", "http://example.com/a/", 200, ""},
+		{"lowercase", Options{Lowercase: true}, "GET", "http://example.com/A/B", 301, "http://example.com/a/b"},
+		{"lowercase post", Options{Lowercase: true}, "DELETE", "http://example.com/A", 308, "http://example.com/a"},
+		{"clean path", Options{CleanPath: true}, "GET", "http://example.com/a//b/../c/", 301, "http://example.com/a/c/"},
+		{"escaped path", Options{Lowercase: true}, "GET", "http://example.com/A%2FB", 301, "http://example.com/a%2fb"},
+		{"skip", Options{HTTPS: true, Skip: func(r *http.Request) bool { return r.URL.Path == "/health" }}, "GET", "http://example.com/health", 200, ""},
+	}
+	for _, tt := range tests {
+		h := route.MiddlewareRunner{
+			Stack: []route.Middleware{New(tt.options)},
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(200)
+			}),
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, w.Code, w.Header().Get("Location"), tt.status, tt.location)
+		}
+	}
+}
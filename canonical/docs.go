@@ -0,0 +1,29 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package canonical redirects requests to their canonical URL. It enforces the
+canonical host and HTTPS scheme, and normalizes trailing slashes, duplicate
+slashes, dot segments, and letter case of the path. GET and HEAD requests are
+redirected with 301, while other methods use 308 so the request body is
+preserved.
+
+The middleware must see requests before routing, so run it in front of the
+router. Behind load balancers, run realip middleware first so the original
+scheme and host are known.
+
+	resolver, _ := realip.New(realip.Options{TrustedProxies: proxies})
+	serve.Use(route.MiddlewareRunner{
+		Stack: []route.Middleware{resolver, canonical.New(canonical.Options{
+			Host:          "www.example.com",
+			HTTPS:         true,
+			TrailingSlash: canonical.SlashRemove,
+			CleanPath:     true,
+		})},
+		Handler: router,
+	})
+*/
+package canonical
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen represents request to host with open circuit
+var ErrCircuitOpen = errors.New("client: Circuit breaker is open")
+
+// State define circuit breaker state of a host.
+type State int
+
+const (
+	// StateClosed passes requests
+	StateClosed State = iota
+	// StateOpen rejects requests until the timeout passes
+	StateOpen
+	// StateHalfOpen passes limited probe requests
+	StateHalfOpen
+)
+
+// String gets name of the state.
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Breaker opens circuit of hosts failing consecutive requests. Failures
+// are transport errors and 5xx responses.
+type Breaker struct {
+	// Threshold of consecutive failures to open the circuit, defaults to 5
+	Threshold int
+	// Timeout before open circuit lets probes through, defaults to 30
+	// seconds
+	Timeout time.Duration
+	// HalfOpenRequests limits concurrent probes, defaults to 1
+	HalfOpenRequests int
+	mu               sync.Mutex
+	circuits         map[string]*circuit
+}
+
+// circuit store breaker state of a host
+type circuit struct {
+	state  State
+	fails  int
+	opened time.Time
+	probes int
+}
+
+// State gets circuit state of the host.
+func (b *Breaker) State(host string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[host]; ok {
+		if c.state == StateOpen && time.Since(c.opened) >= b.timeout() {
+			return StateHalfOpen
+		}
+		return c.state
+	}
+	return StateClosed
+}
+
+// allow checks whether request to the host may be sent.
+func (b *Breaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	switch c.state {
+	case StateOpen:
+		if time.Since(c.opened) < b.timeout() {
+			return false
+		}
+		c.state, c.probes = StateHalfOpen, 0
+		fallthrough
+	case StateHalfOpen:
+		limit := b.HalfOpenRequests
+		if limit <= 0 {
+			limit = 1
+		}
+		if c.probes >= limit {
+			return false
+		}
+		c.probes++
+	}
+	return true
+}
+
+// record records request result of the host.
+func (b *Breaker) record(host string, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	if ok {
+		c.state, c.fails = StateClosed, 0
+		return
+	}
+	c.fails++
+	threshold := b.Threshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if c.state == StateHalfOpen || c.fails >= threshold {
+		c.state, c.opened, c.fails = StateOpen, time.Now(), 0
+	}
+}
+
+// circuit gets circuit of the host, the lock must be held.
+func (b *Breaker) circuit(host string) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+// timeout gets open circuit timeout.
+func (b *Breaker) timeout() time.Duration {
+	if b.Timeout > 0 {
+		return b.Timeout
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package client makes outbound HTTP calls resilient. Its Transport wraps a
+RoundTripper with per-host circuit breaking, retries with exponential
+backoff and jitter that honor Retry-After, per-attempt timeouts derived from
+the request context deadline, and hedged requests for idempotent calls.
+
+	downstream := client.NewClient(client.Options{
+		Breaker: &client.Breaker{Threshold: 5, Timeout: 30 * time.Second},
+		Retry:   client.Retry{Max: 2},
+		Hedge:   50 * time.Millisecond,
+	})
+
+Pass the incoming request context to outbound requests, so retries stop at
+the incoming deadline and the remaining time is forwarded to the downstream
+service in the route.DeadlineHeader header.
+
+	req, _ := http.NewRequestWithContext(r.Context(), "GET", uri, nil)
+	res, err := downstream.Do(req)
+*/
+package client
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,82 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry define retry policy of idempotent requests
+type Retry struct {
+	// Max retries after the first attempt
+	Max int
+	// BaseDelay of exponential backoff, defaults to 100 milliseconds
+	BaseDelay time.Duration
+	// MaxDelay caps backoff delay, defaults to 5 seconds. Responses asking
+	// to retry after longer delay are not retried.
+	MaxDelay time.Duration
+	// Statuses are retried response statuses, defaults to 429, 502, 503,
+	// and 504
+	Statuses []int
+}
+
+// retryStatus checks whether the response status is retried.
+func (p Retry) retryStatus(status int) bool {
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = []int{429, 502, 503, 504}
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// delay gets wait time before the retry with full jitter backoff, or the
+// Retry-After time of the response if longer. It returns false if the
+// Retry-After time exceeds MaxDelay.
+func (p Retry) delay(retry int, res *http.Response) (time.Duration, bool) {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	ceil := base << uint(retry-1)
+	if ceil > max || ceil <= 0 {
+		ceil = max
+	}
+	d := time.Duration(rand.Int63n(int64(ceil) + 1))
+	if res != nil {
+		after := retryAfter(res.Header.Get("Retry-After"))
+		if after > max {
+			return 0, false
+		}
+		if after > d {
+			d = after
+		}
+	}
+	return d, true
+}
+
+// retryAfter parses Retry-After header in seconds or HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
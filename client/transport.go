@@ -0,0 +1,217 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// Options store transport configurations
+type Options struct {
+	// Base sends the requests, defaults to http.DefaultTransport
+	Base http.RoundTripper
+	// Breaker opens circuit of failing hosts, disabled if nil
+	Breaker *Breaker
+	Retry   Retry
+	// AttemptTimeout limits each attempt, shortened to an equal share of
+	// the remaining context deadline
+	AttemptTimeout time.Duration
+	// Hedge sends another attempt of idempotent requests without response
+	// after the delay, disabled if zero
+	Hedge time.Duration
+}
+
+// Transport is resilient http.RoundTripper.
+type Transport struct {
+	Options
+}
+
+// New creates transport.
+func New(o Options) *Transport {
+	return &Transport{Options: o}
+}
+
+// NewClient creates HTTP client with the transport.
+func NewClient(o Options) *http.Client {
+	return &http.Client{Transport: New(o)}
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	attempts := 1
+	if idempotent(req) {
+		attempts += t.Retry.Max
+	}
+	for i := 0; ; i++ {
+		if t.Breaker != nil && !t.Breaker.allow(host) {
+			if i == 0 && req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, ErrCircuitOpen
+		}
+		res, err := t.attempt(req, attempts-i)
+		// Attempts send copies of GetBody, the original body is closed
+		// once as required of round trippers
+		if i == 0 && req.Body != nil && req.GetBody != nil {
+			req.Body.Close()
+		}
+		if t.Breaker != nil {
+			t.Breaker.record(host, err == nil && res.StatusCode < 500)
+		}
+		if err == nil && !t.Retry.retryStatus(res.StatusCode) || i == attempts-1 || ctx.Err() != nil {
+			return res, err
+		}
+		// Give up retrying if the delay is too long or passes the context
+		// deadline
+		d, ok := t.Retry.delay(i+1, res)
+		if !ok {
+			return res, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+			res.Body.Close()
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once, hedging idempotent requests.
+func (t *Transport) attempt(req *http.Request, left int) (*http.Response, error) {
+	ctx, cancel := t.attemptContext(req.Context(), left)
+	if t.Hedge <= 0 || !idempotent(req) {
+		return t.send(ctx, cancel, req)
+	}
+	type result struct {
+		res *http.Response
+		err error
+		i   int
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelFunc
+	launch := func() {
+		actx, acancel := context.WithCancel(ctx)
+		cancels = append(cancels, acancel)
+		i := len(cancels) - 1
+		go func() {
+			res, err := t.send(actx, acancel, req)
+			results <- result{res, err, i}
+		}()
+	}
+	launch()
+	pending := 1
+	timer := time.NewTimer(t.Hedge)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			launch()
+			pending++
+		case r := <-results:
+			pending--
+			if r.err != nil && pending > 0 {
+				continue
+			}
+			if r.err != nil {
+				cancel()
+				return nil, r.err
+			}
+			// Cancel the other attempt and discard its response
+			for i, c := range cancels {
+				if i != r.i {
+					c()
+				}
+			}
+			if pending > 0 {
+				go func() {
+					if r := <-results; r.res != nil {
+						r.res.Body.Close()
+					}
+				}()
+			}
+			r.res.Body = &cancelBody{ReadCloser: r.res.Body, cancel: cancel}
+			return r.res, nil
+		}
+	}
+}
+
+// attemptContext gets context of an attempt with equal share of the
+// remaining deadline among attempts left.
+func (t *Transport) attemptContext(ctx context.Context, left int) (context.Context, context.CancelFunc) {
+	d := t.AttemptTimeout
+	if deadline, ok := ctx.Deadline(); ok && left > 1 {
+		if share := time.Until(deadline) / time.Duration(left); d <= 0 || share < d {
+			d = share
+		}
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// send sends a copy of the request with the context, the context is
+// canceled once the response body is closed.
+func (t *Transport) send(ctx context.Context, cancel context.CancelFunc, req *http.Request) (*http.Response, error) {
+	out := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		out.Body = body
+	}
+	route.SetDeadlineHeader(out)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(out)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody cancels attempt context once closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer interface.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// idempotent checks whether the request can be safely sent again.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+	default:
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeBody counts closes of request body
+type closeBody struct {
+	io.Reader
+	closed int32
+}
+
+func (b *closeBody) Close() error {
+	atomic.AddInt32(&b.closed, 1)
+	return nil
+}
+
+// bodyRequest creates PUT request with GetBody copies of the tracked body.
+func bodyRequest(url string) (*http.Request, *closeBody) {
+	body := &closeBody{Reader: strings.NewReader("data")}
+	req, _ := http.NewRequest("PUT", url, body)
+	req.ContentLength = 4
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("data")), nil
+	}
+	return req, body
+}
+
+func TestTransportRetryBody(t *testing.T) {
+	var hits int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b, _ := io.ReadAll(r.Body); string(b) != "data" {
+			t.Errorf("attempt body %q", b)
+		}
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer s.Close()
+	c := NewClient(Options{Retry: Retry{Max: 2, BaseDelay: time.Millisecond}})
+	req, body := bodyRequest(s.URL)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 || hits != 3 {
+		t.Fatalf("got %d after %d attempts", res.StatusCode, hits)
+	}
+	if body.closed != 1 {
+		t.Fatalf("original body closed %d times", body.closed)
+	}
+}
+
+func TestTransportCircuitOpenBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer s.Close()
+	c := NewClient(Options{Breaker: &Breaker{Threshold: 1, Timeout: time.Minute}})
+	res, err := c.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	req, body := bodyRequest(s.URL)
+	if _, err := c.Do(req); err == nil || !strings.Contains(err.Error(), ErrCircuitOpen.Error()) {
+		t.Fatalf("got %v", err)
+	}
+	if body.closed != 1 {
+		t.Fatalf("original body closed %d times", body.closed)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := Retry{BaseDelay: 10 * time.Millisecond, MaxDelay: 2 * time.Second}
+	for retry := 1; retry < 70; retry++ {
+		if d, ok := p.delay(retry, nil); !ok || d < 0 || d > p.MaxDelay {
+			t.Fatalf("retry %d: got %v %v", retry, d, ok)
+		}
+	}
+	res := &http.Response{Header: http.Header{"Retry-After": {"1"}}}
+	if d, ok := p.delay(1, res); !ok || d != time.Second {
+		t.Fatalf("got %v %v, want Retry-After delay", d, ok)
+	}
+	res.Header.Set("Retry-After", "3")
+	if _, ok := p.delay(1, res); ok {
+		t.Fatal("Retry-After beyond MaxDelay retried")
+	}
+	res.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if _, ok := p.delay(1, res); ok {
+		t.Fatal("Retry-After date beyond MaxDelay retried")
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	var hits int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(503)
+	}))
+	defer s.Close()
+	c := NewClient(Options{Retry: Retry{Max: 2, MaxDelay: time.Second}})
+	start := time.Now()
+	res, err := c.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 503 || hits != 1 || time.Since(start) > time.Second {
+		t.Fatalf("got %d after %d attempts in %v", res.StatusCode, hits, time.Since(start))
+	}
+}
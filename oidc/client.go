@@ -0,0 +1,409 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mandala/omnibus/auth"
+	"github.com/mandala/omnibus/browser"
+	"github.com/mandala/omnibus/route"
+)
+
+// ErrState represents callback with unknown or expired state
+var ErrState = errors.New("oidc: Invalid authorization state")
+
+// ErrNonce represents ID token with mismatched nonce
+var ErrNonce = errors.New("oidc: Invalid ID token nonce")
+
+// ErrNoRefreshToken represents refresh without refresh token
+var ErrNoRefreshToken = errors.New("oidc: Refresh token is not available")
+
+// ErrNotSignedIn represents request without signed in identity
+var ErrNotSignedIn = errors.New("oidc: User is not signed in")
+
+// stateLifetime define how long a login attempt may take
+const stateLifetime = 10 * time.Minute
+
+// Options store relying party configurations
+type Options struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL, either absolute or a path resolved
+	// against the request host
+	RedirectURL string
+	// Scopes defaults to openid, profile and email
+	Scopes []string
+	// PostLogoutURL is where users land after logout, defaults to /
+	PostLogoutURL string
+	// HTTPClient is used for discovery, keys and token requests
+	HTTPClient *http.Client
+	// Leeway tolerates clock skew on ID token validation
+	Leeway time.Duration
+	// Tokens keeps ID, access and refresh tokens on the session for API
+	// calls and token refresh. Tokens easily exceed the size limit of
+	// cookie sessions, use a server-side session store. Only the subject
+	// and the ID token claims are kept otherwise.
+	Tokens bool
+	// CSRF protects the logout route, defaults to session CSRF protection
+	CSRF *browser.CSRF
+}
+
+// Client is OpenID Connect relying party. It is also the middleware that
+// requires signed in users.
+type Client struct {
+	Options
+	Provider *Provider
+	verifier *auth.JWT
+	loginURL string
+}
+
+// tokenResponse define token endpoint response body
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// New discovers the identity provider and creates relying party client.
+func New(ctx context.Context, o Options) (*Client, error) {
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	if len(o.Scopes) == 0 {
+		o.Scopes = []string{"openid", "profile", "email"}
+	}
+	if o.PostLogoutURL == "" {
+		o.PostLogoutURL = "/"
+	}
+	if o.CSRF == nil {
+		o.CSRF = browser.NewCSRF(browser.CSRFOptions{})
+	}
+	p, err := Discover(ctx, o.HTTPClient, o.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	keys := auth.NewJWKS(p.JWKSURI)
+	keys.Client = o.HTTPClient
+	return &Client{
+		Options:  o,
+		Provider: p,
+		verifier: auth.NewJWT(auth.JWTOptions{
+			Keys:       keys,
+			Algorithms: []string{auth.RS256, auth.ES256, auth.EdDSA},
+			Issuer:     p.Issuer,
+			Audience:   o.ClientID,
+			Leeway:     o.Leeway,
+		}),
+		loginURL: "/login",
+	}, nil
+}
+
+// Mount registers login, callback and logout routes on the router. Logout
+// only accepts POST requests with CSRF token, so that other sites cannot
+// sign the user out.
+func (c *Client) Mount(r *route.Router) {
+	login := r.GetFunc("/login", c.Login).Name("oidc.login")
+	r.GetFunc("/callback", c.Callback).Name("oidc.callback")
+	r.Middleware(c.CSRF).PostFunc("/logout", c.Logout).Name("oidc.logout")
+	if u, err := login.URL(); err == nil {
+		c.loginURL = u.Path
+	}
+}
+
+// Login redirects the user to the identity provider. The next query
+// parameter sets the local path to return to after signing in.
+func (c *Client) Login(w http.ResponseWriter, r *http.Request) {
+	s := browser.GetSession(r)
+	if s == nil {
+		http.Error(w, "500 Internal Server Error", 500)
+		return
+	}
+	st := &loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		ReturnTo: localPath(r.URL.Query().Get("next")),
+		Created:  time.Now(),
+	}
+	s.Set(stateKey, st)
+	challenge := sha256.Sum256([]byte(st.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.redirectURL(r)},
+		"scope":                 {strings.Join(c.Scopes, " ")},
+		"state":                 {st.State},
+		"nonce":                 {st.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, appendQuery(c.Provider.AuthorizationEndpoint, q), 302)
+}
+
+// Callback completes the authorization code flow and signs the user in.
+func (c *Client) Callback(w http.ResponseWriter, r *http.Request) {
+	s := browser.GetSession(r)
+	if s == nil {
+		http.Error(w, "500 Internal Server Error", 500)
+		return
+	}
+	st, _ := s.Get(stateKey).(*loginState)
+	s.Delete(stateKey)
+	q := r.URL.Query()
+	if st == nil || time.Since(st.Created) > stateLifetime ||
+		subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		http.Error(w, "400 Bad Request - "+ErrState.Error(), 400)
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "401 Unauthorized - "+e, 401)
+		return
+	}
+	// Exchange authorization code with PKCE verifier
+	tokens, err := c.token(r.Context(), url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {q.Get("code")},
+		"redirect_uri":  {c.redirectURL(r)},
+		"code_verifier": {st.Verifier},
+	})
+	if err != nil {
+		http.Error(w, "502 Bad Gateway - "+err.Error(), 502)
+		return
+	}
+	identity, err := c.identity(r.Context(), tokens, st.Nonce)
+	if err != nil {
+		http.Error(w, "401 Unauthorized - "+err.Error(), 401)
+		return
+	}
+	// Prevent session fixation on sign in
+	s.Regenerate()
+	s.Set(identityKey, identity)
+	http.Redirect(w, r, st.ReturnTo, 303)
+}
+
+// Logout signs the user out locally and at the identity provider, if it
+// supports RP-initiated logout. It must be served behind CSRF protection.
+func (c *Client) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "405 Method Not Allowed", 405)
+		return
+	}
+	target := c.PostLogoutURL
+	if s := browser.GetSession(r); s != nil {
+		identity, _ := s.Get(identityKey).(*Identity)
+		s.Destroy()
+		if identity != nil && c.Provider.EndSessionEndpoint != "" {
+			q := url.Values{
+				"client_id":                {c.ClientID},
+				"post_logout_redirect_uri": {c.absoluteURL(r, c.PostLogoutURL)},
+			}
+			if identity.IDToken != "" {
+				q.Set("id_token_hint", identity.IDToken)
+			}
+			target = appendQuery(c.Provider.EndSessionEndpoint, q)
+		}
+	}
+	http.Redirect(w, r, target, 303)
+}
+
+// Refresh renews the access token of the signed in user with the refresh
+// token and updates the session.
+func (c *Client) Refresh(r *http.Request) (*Identity, error) {
+	s := browser.GetSession(r)
+	if s == nil {
+		return nil, ErrNotSignedIn
+	}
+	old, _ := s.Get(identityKey).(*Identity)
+	if old == nil {
+		return nil, ErrNotSignedIn
+	}
+	if old.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+	tokens, err := c.token(r.Context(), url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {old.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	identity := *old
+	identity.AccessToken = tokens.AccessToken
+	identity.Expiry = expiry(tokens.ExpiresIn)
+	if tokens.RefreshToken != "" {
+		identity.RefreshToken = tokens.RefreshToken
+	}
+	// Validate renewed ID token of the same subject, if issued
+	if tokens.IDToken != "" {
+		claims, err := c.verifier.Verify(r.Context(), tokens.IDToken)
+		if err != nil {
+			return nil, err
+		}
+		if claims.Subject() != old.Subject {
+			return nil, auth.ErrInvalidCredentials
+		}
+		identity.IDToken = tokens.IDToken
+		identity.RawClaims, _ = json.Marshal(claims)
+	}
+	s.Set(identityKey, &identity)
+	return &identity, nil
+}
+
+// ServeHTTP implements route.Middleware interface. Anonymous users are
+// redirected to the login route, and expired access tokens are refreshed.
+func (c *Client) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	identity := GetIdentity(r)
+	if identity != nil && identity.Expired() {
+		var err error
+		if identity, err = c.Refresh(r); err != nil {
+			identity = nil
+		}
+	}
+	if identity == nil {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			http.Error(w, "401 Unauthorized", 401)
+			return
+		}
+		http.Redirect(w, r, c.loginURL+"?"+url.Values{"next": {r.URL.RequestURI()}}.Encode(), 302)
+		return
+	}
+	next.ServeHTTP(w, route.WithPrincipal(r, identity.Principal()))
+}
+
+// Authenticate implements route.Authenticator interface, so the signed in
+// session can be combined with other authentication schemes.
+func (c *Client) Authenticate(r *http.Request) (*route.Principal, error) {
+	identity := GetIdentity(r)
+	if identity == nil {
+		return nil, route.ErrNoCredentials
+	}
+	if identity.Expired() {
+		var err error
+		if identity, err = c.Refresh(r); err != nil {
+			return nil, err
+		}
+	}
+	return identity.Principal(), nil
+}
+
+// identity validates the ID token and creates identity from tokens.
+func (c *Client) identity(ctx context.Context, tokens *tokenResponse, nonce string) (*Identity, error) {
+	claims, err := c.verifier.Verify(ctx, tokens.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.GetString("nonce")), []byte(nonce)) != 1 {
+		return nil, ErrNonce
+	}
+	if aud := claims.Audience(); len(aud) > 1 && claims.GetString("azp") != c.ClientID {
+		return nil, auth.ErrAudience
+	}
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{Subject: claims.Subject(), RawClaims: raw}
+	if c.Tokens {
+		identity.IDToken = tokens.IDToken
+		identity.AccessToken = tokens.AccessToken
+		identity.RefreshToken = tokens.RefreshToken
+		identity.Expiry = expiry(tokens.ExpiresIn)
+	}
+	return identity, nil
+}
+
+// token posts the grant to the token endpoint.
+func (c *Client) token(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	if c.ClientSecret == "" {
+		form.Set("client_id", c.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	tokens := &tokenResponse{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(tokens); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != 200 || tokens.Error != "" {
+		return nil, fmt.Errorf("oidc: Token request failed: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	return tokens, nil
+}
+
+// redirectURL gets absolute callback URL.
+func (c *Client) redirectURL(r *http.Request) string {
+	return c.absoluteURL(r, c.RedirectURL)
+}
+
+// absoluteURL resolves path against the request scheme and host.
+func (c *Client) absoluteURL(r *http.Request, path string) string {
+	if strings.HasPrefix(path, "/") {
+		return route.RequestScheme(r) + "://" + r.Host + path
+	}
+	return path
+}
+
+// appendQuery adds query parameters to the endpoint URL.
+func appendQuery(endpoint string, q url.Values) string {
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + q.Encode()
+	}
+	return endpoint + "?" + q.Encode()
+}
+
+// localPath allows only local absolute paths to prevent open redirects.
+func localPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return "/"
+	}
+	return p
+}
+
+// expiry gets absolute expiry time of the token lifetime.
+func expiry(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+// randomString generates random URL-safe string.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mandala/omnibus/browser"
+	"github.com/mandala/omnibus/route"
+)
+
+// testIdP is stand-in identity provider issuing ES256 ID tokens
+type testIdP struct {
+	*httptest.Server
+	key      *ecdsa.PrivateKey
+	clientID string
+	// codes maps issued authorization codes to their nonce and challenge
+	codes map[string][2]string
+}
+
+func newTestIdP(t *testing.T, clientID string) *testIdP {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testIdP{key: key, clientID: clientID, codes: make(map[string][2]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+			"end_session_endpoint":   p.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "EC", "kid": "k1", "use": "sig", "crv": "P-256",
+			"x": enc.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y": enc.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		code := "code-" + q.Get("state")[:8]
+		p.codes[code] = [2]string{q.Get("nonce"), q.Get("code_challenge")}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), 302)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		grant, ok := p.codes[r.PostForm.Get("code")]
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant[1] {
+			w.WriteHeader(400)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(p.codes, r.PostForm.Get("code"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  strings.Repeat("a", 3000),
+			"refresh_token": strings.Repeat("r", 1000),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"id_token":      p.sign(t, grant[0]),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *testIdP) sign(t *testing.T, nonce string) string {
+	enc := base64.RawURLEncoding
+	h, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "k1"})
+	c, _ := json.Marshal(map[string]interface{}{
+		"iss": p.URL, "aud": p.clientID, "sub": "alice", "nonce": nonce,
+		"roles": []string{"admin"}, "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+	sum := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + enc.EncodeToString(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+}
+
+// newTestApp creates application with cookie sessions signed in by the
+// identity provider.
+func newTestApp(t *testing.T, idp *testIdP, tokens bool) *httptest.Server {
+	client, err := New(context.Background(), Options{
+		Issuer:      idp.URL,
+		ClientID:    "dashboard",
+		RedirectURL: "/auth/callback",
+		Tokens:      tokens,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, _ := browser.NewEncrypter(bytes.Repeat([]byte{7}, 32))
+	sessions := browser.NewSessions(browser.NewCookieStore(codec), browser.Options{})
+	csrf := browser.NewCSRF(browser.CSRFOptions{})
+	router := route.NewRouter()
+	router.Middleware(sessions, csrf).Group(func(r *route.Router) {
+		client.Mount(r.PathPrefix("/auth").Subrouter())
+		r.PathPrefix("/").Middleware(client).Group(func(r *route.Router) {
+			r.GetFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				p := route.GetPrincipal(r)
+				io.WriteString(w, p.ID+" "+strings.Join(p.Roles, ",")+" "+browser.CSRFToken(r))
+			})
+		})
+	})
+	return httptest.NewServer(router)
+}
+
+func TestLoginFlow(t *testing.T) {
+	idp := newTestIdP(t, "dashboard")
+	defer idp.Close()
+	app := newTestApp(t, idp, false)
+	defer app.Close()
+
+	jar, _ := cookiejar.New(nil)
+	c := &http.Client{Jar: jar}
+	// Anonymous users are redirected through the identity provider back
+	// to the requested page
+	res, err := c.Get(app.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	fields := strings.Fields(string(body))
+	if res.StatusCode != 200 || len(fields) != 3 || fields[0] != "alice" || fields[1] != "admin" {
+		t.Fatalf("got %d %q", res.StatusCode, body)
+	}
+
+	// Logout requires POST with CSRF token
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, _ = c.Get(app.URL + "/auth/logout")
+	res.Body.Close()
+	if res.StatusCode == 303 {
+		t.Fatal("GET logout signed the user out")
+	}
+	res, _ = c.PostForm(app.URL+"/auth/logout", nil)
+	res.Body.Close()
+	if res.StatusCode != 403 {
+		t.Fatalf("logout without token: got %d, want 403", res.StatusCode)
+	}
+	res, _ = c.Get(app.URL + "/")
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Fatalf("rejected logout signed the user out: got %d", res.StatusCode)
+	}
+	req, _ := http.NewRequest("POST", app.URL+"/auth/logout", nil)
+	req.Header.Set("X-CSRF-Token", fields[2])
+	req.Header.Set("Origin", app.URL)
+	res, _ = c.Do(req)
+	res.Body.Close()
+	if res.StatusCode != 303 || !strings.HasPrefix(res.Header.Get("Location"), idp.URL+"/logout?") {
+		t.Fatalf("logout: got %d to %q", res.StatusCode, res.Header.Get("Location"))
+	}
+	res, _ = c.Get(app.URL + "/")
+	res.Body.Close()
+	if res.StatusCode != 302 {
+		t.Fatalf("after logout: got %d, want 302", res.StatusCode)
+	}
+}
+
+func TestLoginFlowTokensTooLarge(t *testing.T) {
+	idp := newTestIdP(t, "dashboard")
+	defer idp.Close()
+	app := newTestApp(t, idp, true)
+	defer app.Close()
+
+	// Tokens do not fit in the cookie session, the callback fails loudly
+	// instead of losing the identity on the next request
+	jar, _ := cookiejar.New(nil)
+	c := &http.Client{Jar: jar}
+	res, err := c.Get(app.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 500 || res.Request.URL.Path != "/auth/callback" {
+		t.Fatalf("got %d on %s, want 500 on callback", res.StatusCode, res.Request.URL.Path)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package oidc implements OpenID Connect relying party for browser
+applications. It discovers the identity provider configuration, runs the
+authorization code flow with PKCE, state and nonce, exchanges the code for
+tokens, validates the ID token, and stores the identity on the browser
+session. Only the subject and the ID token claims are stored unless the
+Tokens option is set, in which case access tokens are refreshed
+automatically when they expire. Tokens do not fit in cookie sessions, use a
+server-side session store for them.
+
+	client, err := oidc.New(ctx, oidc.Options{
+		Issuer:       "https://id.example.com",
+		ClientID:     "dashboard",
+		ClientSecret: secret,
+		RedirectURL:  "/auth/callback",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Middleware(sessions).Group(func(r *route.Router) {
+		client.Mount(r.PathPrefix("/auth").Subrouter())
+		r.PathPrefix("/").Middleware(client).Group(func(r *route.Router) {
+			r.GetFunc("/", dashboard)
+		})
+	})
+
+The login, callback, and logout routes require the browser session
+middleware. Logout accepts POST requests with CSRF token only, submitted
+from a form with browser.CSRFField. Routes protected by the client
+middleware redirect anonymous users to the login route and get the identity
+as route principal.
+*/
+package oidc
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package oidc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mandala/omnibus/auth"
+	"github.com/mandala/omnibus/browser"
+	"github.com/mandala/omnibus/route"
+)
+
+// Session keys of the login state and the identity
+const (
+	stateKey    = "_oidc_state"
+	identityKey = "_oidc_identity"
+)
+
+// loginState store pending authorization request on the session
+type loginState struct {
+	State    string
+	Nonce    string
+	Verifier string
+	ReturnTo string
+	Created  time.Time
+}
+
+// Identity store the signed in user on the session. Tokens are kept only
+// with Tokens option.
+type Identity struct {
+	Subject      string
+	IDToken      string
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+	RawClaims    []byte
+}
+
+func init() {
+	// Register session value types
+	gob.Register(&loginState{})
+	gob.Register(&Identity{})
+}
+
+// Claims gets the verified ID token claims.
+func (i *Identity) Claims() auth.Claims {
+	var claims auth.Claims
+	dec := json.NewDecoder(bytes.NewReader(i.RawClaims))
+	dec.UseNumber()
+	dec.Decode(&claims)
+	return claims
+}
+
+// Expired checks whether the access token already expired.
+func (i *Identity) Expired() bool {
+	return !i.Expiry.IsZero() && time.Now().After(i.Expiry)
+}
+
+// Principal gets route principal of the identity.
+func (i *Identity) Principal() *route.Principal {
+	p := i.Claims().Principal()
+	p.Scheme = "oidc"
+	return p
+}
+
+// GetIdentity gets signed in identity from the request session.
+func GetIdentity(r *http.Request) *Identity {
+	s := browser.GetSession(r)
+	if s == nil {
+		return nil
+	}
+	i, _ := s.Get(identityKey).(*Identity)
+	return i
+}
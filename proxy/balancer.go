@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package proxy
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/mandala/omnibus/realip"
+)
+
+// Balancer picks an upstream of the request from available upstreams.
+type Balancer interface {
+	Pick(r *http.Request, upstreams []*Upstream) *Upstream
+}
+
+// RoundRobin picks upstreams in turn.
+type RoundRobin struct {
+	next uint64
+}
+
+// Pick implements proxy.Balancer interface.
+func (b *RoundRobin) Pick(r *http.Request, upstreams []*Upstream) *Upstream {
+	n := atomic.AddUint64(&b.next, 1)
+	return upstreams[(n-1)%uint64(len(upstreams))]
+}
+
+// LeastConn picks upstream with the least active requests, ties are broken
+// in turn.
+type LeastConn struct {
+	next uint64
+}
+
+// Pick implements proxy.Balancer interface.
+func (b *LeastConn) Pick(r *http.Request, upstreams []*Upstream) *Upstream {
+	n := int(atomic.AddUint64(&b.next, 1) % uint64(len(upstreams)))
+	var best *Upstream
+	for i := range upstreams {
+		u := upstreams[(n+i)%len(upstreams)]
+		if best == nil || u.Active() < best.Active() {
+			best = u
+		}
+	}
+	return best
+}
+
+// ConsistentHash picks the same upstream for requests of the same key with
+// rendezvous hashing, so only keys of an unavailable upstream move.
+type ConsistentHash struct {
+	// Key gets hash key of the request, defaults to client IP
+	Key func(*http.Request) string
+}
+
+// Pick implements proxy.Balancer interface.
+func (b *ConsistentHash) Pick(r *http.Request, upstreams []*Upstream) *Upstream {
+	key := b.Key
+	if key == nil {
+		key = realip.ClientIP
+	}
+	k := key(r)
+	var best *Upstream
+	var max uint64
+	for _, u := range upstreams {
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		h.Write([]byte(u.URL.Host))
+		if s := h.Sum64(); best == nil || s > max {
+			best, max = u, s
+		}
+	}
+	return best
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package proxy forwards requests to upstream servers, so legacy backends can
+be served from the same router. Upstreams are picked by a Balancer, such as
+RoundRobin, LeastConn, or ConsistentHash. Upstreams failing consecutive
+requests are taken out of rotation for a while, and active health checks
+probe them periodically. Idempotent requests are retried on other upstreams
+when an upstream fails. WebSocket and other upgraded connections are passed
+through.
+
+	legacy, err := proxy.New(proxy.Options{
+		Upstreams:   []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"},
+		Balancer:    &proxy.LeastConn{},
+		StripPrefix: "/legacy",
+		Retries:     2,
+		HealthCheck: proxy.HealthCheck{Path: "/healthz", Interval: 5 * time.Second},
+	})
+	defer legacy.Close()
+	router.PathPrefix("/legacy/").Handler(legacy)
+*/
+package proxy
+
+// This file is intentionally left blank for Godoc documentation.
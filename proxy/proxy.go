@@ -0,0 +1,276 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoUpstream represents request without available upstream
+var ErrNoUpstream = errors.New("proxy: No upstream is available")
+
+// Options store proxy configurations
+type Options struct {
+	Upstreams []string
+	// Balancer picks upstreams, defaults to round-robin
+	Balancer Balancer
+	// StripPrefix removes the prefix from request path
+	StripPrefix string
+	// RewritePath changes request path after the prefix is stripped
+	RewritePath func(string) string
+	// PreserveHost sends the request host instead of the upstream host
+	PreserveHost bool
+	// Retries of idempotent requests on other upstreams
+	Retries int
+	// MaxFails takes an upstream out of rotation after consecutive failed
+	// requests, defaults to 3
+	MaxFails int
+	// FailTimeout is the time failed upstreams stay out of rotation,
+	// defaults to 10 seconds
+	FailTimeout time.Duration
+	HealthCheck HealthCheck
+	// Transport sends requests to upstreams, defaults to
+	// http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Proxy is the handler that forwards requests to upstreams.
+type Proxy struct {
+	Options
+	upstreams []*Upstream
+	proxy     *httputil.ReverseProxy
+	cancel    context.CancelFunc
+}
+
+// New creates proxy handler and starts health checks of its upstreams.
+func New(o Options) (*Proxy, error) {
+	if len(o.Upstreams) == 0 {
+		return nil, ErrNoUpstream
+	}
+	if o.Balancer == nil {
+		o.Balancer = &RoundRobin{}
+	}
+	if o.MaxFails <= 0 {
+		o.MaxFails = 3
+	}
+	if o.FailTimeout <= 0 {
+		o.FailTimeout = 10 * time.Second
+	}
+	if o.Transport == nil {
+		o.Transport = http.DefaultTransport
+	}
+	p := &Proxy{Options: o}
+	for _, raw := range o.Upstreams {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		p.upstreams = append(p.upstreams, &Upstream{URL: u})
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite:      p.rewrite,
+		Transport:    transport{p},
+		ErrorHandler: p.fail,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	if o.HealthCheck.Path != "" {
+		go o.HealthCheck.check(ctx, &http.Client{Transport: o.Transport}, p.upstreams)
+	}
+	return p, nil
+}
+
+// Upstreams gets upstreams of the proxy.
+func (p *Proxy) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// Close stops health checks of the proxy.
+func (p *Proxy) Close() error {
+	p.cancel()
+	return nil
+}
+
+// ServeHTTP implements http.Handler interface.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.proxy.ServeHTTP(w, r)
+}
+
+// rewrite prepares outbound request path and forwarding headers, the
+// upstream is picked by the transport.
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	path := pr.In.URL.Path
+	if p.StripPrefix != "" {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(p.StripPrefix, "/"))
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if p.RewritePath != nil {
+		path = p.RewritePath(path)
+	}
+	pr.Out.URL.Path, pr.Out.URL.RawPath = path, ""
+	pr.SetXForwarded()
+	if p.PreserveHost {
+		pr.Out.Host = pr.In.Host
+	} else {
+		pr.Out.Host = ""
+	}
+}
+
+// fail writes error response of failed proxy request.
+func (p *Proxy) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		// Client is gone, there is nobody to respond
+		return
+	}
+	if errors.Is(err, ErrNoUpstream) {
+		http.Error(w, http.StatusText(503), 503)
+		return
+	}
+	http.Error(w, http.StatusText(502), 502)
+}
+
+// available gets upstreams in rotation without the excluded ones.
+func (p *Proxy) available(exclude map[*Upstream]bool) []*Upstream {
+	var list []*Upstream
+	for _, u := range p.upstreams {
+		if !exclude[u] && u.Healthy() {
+			list = append(list, u)
+		}
+	}
+	return list
+}
+
+// transport sends requests to upstreams picked by the balancer, retrying
+// idempotent requests on other upstreams
+type transport struct {
+	p *Proxy
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.p
+	tried := make(map[*Upstream]bool)
+	retries := 0
+	if idempotent(req) {
+		retries = p.Retries
+	}
+	var lastErr error = ErrNoUpstream
+	for attempt := 0; attempt <= retries; attempt++ {
+		list := p.available(tried)
+		if len(list) == 0 {
+			break
+		}
+		u := p.Balancer.Pick(req, list)
+		tried[u] = true
+
+		out := req.Clone(req.Context())
+		out.URL.Scheme, out.URL.Host = u.URL.Scheme, u.URL.Host
+		out.URL.Path = singleJoin(u.URL.Path, req.URL.Path)
+		out.URL.RawPath = ""
+		if u.URL.RawQuery != "" && req.URL.RawQuery != "" {
+			out.URL.RawQuery = u.URL.RawQuery + "&" + req.URL.RawQuery
+		} else if u.URL.RawQuery != "" {
+			out.URL.RawQuery = u.URL.RawQuery
+		}
+
+		atomic.AddInt64(&u.active, 1)
+		res, err := p.Transport.RoundTrip(out)
+		if err != nil {
+			atomic.AddInt64(&u.active, -1)
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			u.failure(p.MaxFails, p.FailTimeout)
+			lastErr = err
+			continue
+		}
+		if res.StatusCode >= 502 && res.StatusCode <= 504 {
+			u.failure(p.MaxFails, p.FailTimeout)
+			if attempt < retries && len(p.available(tried)) > 0 {
+				// Retry unavailable upstream responses of idempotent
+				// requests, the response is kept if no other upstream is
+				// left
+				res.Body.Close()
+				atomic.AddInt64(&u.active, -1)
+				lastErr = errors.New("proxy: Upstream responded " + res.Status)
+				continue
+			}
+		} else {
+			u.success()
+		}
+		res.Body = trackBody(res.Body, &u.active)
+		return res, nil
+	}
+	return nil, lastErr
+}
+
+// idempotent checks whether the request can be safely retried.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+	default:
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 && r.GetBody != nil
+}
+
+// trackBody decrements the active counter once the body is closed,
+// keeping upgraded connection bodies writable.
+func trackBody(body io.ReadCloser, active *int64) io.ReadCloser {
+	t := &trackedBody{ReadCloser: body, active: active}
+	if rw, ok := body.(io.ReadWriteCloser); ok {
+		return &trackedConn{trackedBody: t, w: rw}
+	}
+	return t
+}
+
+// trackedBody store response body counted as active request
+type trackedBody struct {
+	io.ReadCloser
+	active *int64
+	closed int32
+}
+
+// Close implements io.Closer interface.
+func (b *trackedBody) Close() error {
+	if atomic.CompareAndSwapInt32(&b.closed, 0, 1) {
+		atomic.AddInt64(b.active, -1)
+	}
+	return b.ReadCloser.Close()
+}
+
+// trackedConn store upgraded connection body
+type trackedConn struct {
+	*trackedBody
+	w io.Writer
+}
+
+// Write implements io.Writer interface.
+func (c *trackedConn) Write(b []byte) (int, error) {
+	return c.w.Write(b)
+}
+
+// singleJoin joins URL paths with a single slash.
+func singleJoin(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case strings.HasSuffix(a, "/") && strings.HasPrefix(b, "/"):
+		return a + b[1:]
+	case !strings.HasSuffix(a, "/") && !strings.HasPrefix(b, "/"):
+		return a + "/" + b
+	}
+	return a + b
+}
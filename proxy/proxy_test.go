@@ -0,0 +1,218 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upstream starts test upstream responding with its name and counting
+// requests.
+func upstream(t *testing.T, name string, status int, hits *int64) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			atomic.AddInt64(hits, 1)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, name+" "+r.URL.Path)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// deadURL gets URL of a closed server, requests to it fail to connect.
+func deadURL() string {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	return s.URL
+}
+
+func serve(p *Proxy, method, path string) (int, string) {
+	var body io.Reader
+	if method == "POST" {
+		body = strings.NewReader("data")
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(method, path, body))
+	return w.Code, w.Body.String()
+}
+
+func TestProxyRetry(t *testing.T) {
+	live := upstream(t, "live", 200, nil)
+	p, err := New(Options{Upstreams: []string{deadURL(), live.URL}, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if code, body := serve(p, "GET", "/a"); code != 200 || body != "live /a" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	// Requests with body are not retried
+	p.Balancer = &RoundRobin{}
+	if code, _ := serve(p, "POST", "/a"); code != 502 {
+		t.Fatalf("POST got %d", code)
+	}
+}
+
+func TestProxyRetryUnavailable(t *testing.T) {
+	var hits int64
+	bad := upstream(t, "bad", 503, &hits)
+	live := upstream(t, "live", 200, nil)
+	p, err := New(Options{Upstreams: []string{bad.URL, live.URL}, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if code, body := serve(p, "GET", "/"); code != 200 || body != "live /" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	// The last attempt responds as is
+	p, err = New(Options{Upstreams: []string{bad.URL}, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if code, body := serve(p, "GET", "/"); code != 503 || body != "bad /" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	if hits != 2 {
+		t.Fatalf("bad upstream hit %d times", hits)
+	}
+	for _, u := range p.Upstreams() {
+		if u.Active() != 0 {
+			t.Fatalf("%s has %d active requests", u.URL, u.Active())
+		}
+	}
+}
+
+func TestProxyPassiveHealth(t *testing.T) {
+	var hits int64
+	live := upstream(t, "live", 200, &hits)
+	p, err := New(Options{
+		Upstreams:   []string{deadURL(), live.URL},
+		MaxFails:    2,
+		FailTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	dead := p.Upstreams()[0]
+	// Round robin alternates, failing the dead upstream twice
+	for i := 0; i < 4; i++ {
+		serve(p, "GET", "/")
+	}
+	if dead.Healthy() {
+		t.Fatal("dead upstream still in rotation")
+	}
+	hits = 0
+	for i := 0; i < 4; i++ {
+		if code, _ := serve(p, "GET", "/"); code != 200 {
+			t.Fatalf("request %d got %d", i, code)
+		}
+	}
+	if hits != 4 {
+		t.Fatalf("live upstream hit %d times", hits)
+	}
+}
+
+func TestProxyPassiveHealthUnavailable(t *testing.T) {
+	bad := upstream(t, "bad", 502, nil)
+	live := upstream(t, "live", 200, nil)
+	p, err := New(Options{
+		Upstreams:   []string{bad.URL, live.URL},
+		MaxFails:    2,
+		FailTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	bu := p.Upstreams()[0]
+	// Requests with body are not retried, the response still counts as
+	// failure of the upstream
+	for i := 0; i < 4; i++ {
+		serve(p, "POST", "/")
+	}
+	if bu.Healthy() {
+		t.Fatal("unavailable upstream still in rotation")
+	}
+	for i := 0; i < 4; i++ {
+		if code, body := serve(p, "POST", "/"); code != 200 || body != "live /" {
+			t.Fatalf("request %d got %d %q", i, code, body)
+		}
+	}
+}
+
+func TestProxyNoUpstream(t *testing.T) {
+	p, err := New(Options{Upstreams: []string{deadURL()}, MaxFails: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if code, _ := serve(p, "GET", "/"); code != 502 {
+		t.Fatalf("failed upstream got %d", code)
+	}
+	if code, _ := serve(p, "GET", "/"); code != 503 {
+		t.Fatalf("no upstream got %d", code)
+	}
+	if _, err := New(Options{}); err != ErrNoUpstream {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestProxyHealthCheck(t *testing.T) {
+	var healthy int32 = 1
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/health" && atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer s.Close()
+	p, err := New(Options{
+		Upstreams:   []string{s.URL + "/api"},
+		HealthCheck: HealthCheck{Path: "/health", Interval: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	u := p.Upstreams()[0]
+	wait := func(want bool) {
+		t.Helper()
+		for i := 0; i < 1000; i++ {
+			if u.Healthy() == want {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("healthy %v, want %v", u.Healthy(), want)
+	}
+	atomic.StoreInt32(&healthy, 0)
+	wait(false)
+	if code, _ := serve(p, "GET", "/"); code != 503 {
+		t.Fatalf("unhealthy upstream got %d", code)
+	}
+	atomic.StoreInt32(&healthy, 1)
+	wait(true)
+}
+
+func TestProxyStripPrefix(t *testing.T) {
+	live := upstream(t, "live", 200, nil)
+	p, err := New(Options{Upstreams: []string{live.URL + "/v1"}, StripPrefix: "/api/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if code, body := serve(p, "GET", "/api/users"); code != 200 || body != "live /v1/users" {
+		t.Fatalf("got %d %q", code, body)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Upstream store state of an upstream server
+type Upstream struct {
+	URL    *url.URL
+	active int64
+	mu     sync.Mutex
+	fails  int
+	down   time.Time
+	probed bool
+}
+
+// Active gets number of active requests on the upstream.
+func (u *Upstream) Active() int64 {
+	return atomic.LoadInt64(&u.active)
+}
+
+// Healthy checks whether the upstream is in rotation.
+func (u *Upstream) Healthy() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !u.probed && time.Now().After(u.down)
+}
+
+// failure records failed request, taking the upstream out of rotation
+// after maxFails consecutive failures.
+func (u *Upstream) failure(maxFails int, timeout time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.fails++
+	if u.fails >= maxFails {
+		u.down = time.Now().Add(timeout)
+		u.fails = 0
+	}
+}
+
+// success records successful request.
+func (u *Upstream) success() {
+	u.mu.Lock()
+	u.fails = 0
+	u.mu.Unlock()
+}
+
+// setProbed sets result of the active health check.
+func (u *Upstream) setProbed(healthy bool) {
+	u.mu.Lock()
+	u.probed = !healthy
+	u.mu.Unlock()
+}
+
+// HealthCheck define active health check of upstreams
+type HealthCheck struct {
+	// Path is requested on upstreams, health checks are disabled if empty
+	Path string
+	// Interval between checks, defaults to 10 seconds
+	Interval time.Duration
+	// Timeout of each check, defaults to 2 seconds
+	Timeout time.Duration
+}
+
+// check probes the upstreams until the context is done.
+func (h HealthCheck) check(ctx context.Context, client *http.Client, upstreams []*Upstream) {
+	interval, timeout := h.Interval, h.Timeout
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, u := range upstreams {
+			go h.probe(ctx, client, u, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe checks a single upstream, 2xx and 3xx responses are healthy.
+func (h HealthCheck) probe(ctx context.Context, client *http.Client, u *Upstream, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	target := *u.URL
+	target.Path = singleJoin(target.Path, h.Path)
+	req, err := http.NewRequestWithContext(ctx, "GET", target.String(), nil)
+	if err != nil {
+		return
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil || ctx.Err() == context.DeadlineExceeded {
+			u.setProbed(false)
+		}
+		return
+	}
+	res.Body.Close()
+	u.setProbed(res.StatusCode >= 200 && res.StatusCode < 400)
+}
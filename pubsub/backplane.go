@@ -0,0 +1,46 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package pubsub
+
+import (
+	"sync"
+)
+
+// Backplane shares published messages between hubs of multiple processes.
+// Publish must deliver the message to receivers of all hubs, including the
+// publisher, which ignores its own messages by the origin.
+type Backplane interface {
+	Publish(m Message) error
+	Receive(f func(Message))
+}
+
+// MemoryBackplane shares messages between hubs of the same process, such
+// as hubs of multiple servers in tests.
+type MemoryBackplane struct {
+	mu        sync.RWMutex
+	receivers []func(Message)
+}
+
+// NewMemoryBackplane creates memory backplane.
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{}
+}
+
+// Publish implements pubsub.Backplane interface.
+func (b *MemoryBackplane) Publish(m Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, f := range b.receivers {
+		f(m)
+	}
+	return nil
+}
+
+// Receive implements pubsub.Backplane interface.
+func (b *MemoryBackplane) Receive(f func(Message)) {
+	b.mu.Lock()
+	b.receivers = append(b.receivers, f)
+	b.mu.Unlock()
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package pubsub broadcasts messages to topics, such as per user or per room
+channels of WebSocket and SSE connections. Each subscription has a bounded
+buffer, and a slow consumer policy decides whether messages are dropped or
+the subscription is disconnected when the buffer is full. Subscriptions with
+a presence key tell who is present on a topic of the hub.
+
+	hub := pubsub.NewHub(nil)
+	sub := hub.Subscribe(pubsub.Options{Key: userID, Policy: pubsub.DropOldest},
+		"room:lobby", "user:"+userID)
+	defer sub.Close()
+	for m := range sub.C {
+		conn.WriteMessage(websocket.TextMessage, m.Data)
+	}
+
+	hub.Publish("room:lobby", []byte("hello"))
+
+Hubs of multiple processes share topics through a Backplane, such as a
+message broker adapter implementing the interface. Presence is tracked per
+hub, OnPresence tells joins and leaves of the hub so they can be published
+to other hubs.
+
+	hub.OnPresence = func(topic, key string, present bool) {
+		event := "leave"
+		if present {
+			event = "join"
+		}
+		hub.Publish("presence:"+topic, []byte(event+" "+key))
+	}
+*/
+package pubsub
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,350 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSlowConsumer represents subscription disconnected for its full buffer
+var ErrSlowConsumer = errors.New("pubsub: Subscriber is too slow")
+
+// ErrClosed represents subscription closed by the subscriber
+var ErrClosed = errors.New("pubsub: Subscription is closed")
+
+// Message store data published to a topic
+type Message struct {
+	Topic string
+	Data  []byte
+	// Origin is the hub that published the message
+	Origin string
+}
+
+// Policy define what happens when subscription buffer is full.
+type Policy int
+
+const (
+	// Drop drops the new message
+	Drop Policy = iota
+	// DropOldest drops the oldest buffered message
+	DropOldest
+	// Disconnect closes the subscription with ErrSlowConsumer
+	Disconnect
+)
+
+// Options store subscription configurations
+type Options struct {
+	// Buffer size of the subscription, defaults to 64
+	Buffer int
+	Policy Policy
+	// Key identifies the subscriber for presence, such as user ID
+	Key string
+}
+
+// Hub delivers published messages to subscriptions of the topic.
+type Hub struct {
+	// ID identifies the hub on the backplane
+	ID string
+	// OnPresence is called once the first subscription of a key joins the
+	// topic on this hub, and once the last one leaves. It is not called
+	// for subscriptions of other hubs, publish the change to share it.
+	OnPresence func(topic, key string, present bool)
+	mu         sync.RWMutex
+	topics     map[string]map[*Subscription]struct{}
+	present    map[string]map[string]int
+	backplane  Backplane
+}
+
+// presence store presence change of a key on a topic
+type presence struct {
+	topic   string
+	key     string
+	present bool
+}
+
+// NewHub creates hub sharing topics through the backplane, or local hub if
+// the backplane is nil.
+func NewHub(b Backplane) *Hub {
+	id := make([]byte, 8)
+	rand.Read(id)
+	h := &Hub{
+		ID:        hex.EncodeToString(id),
+		topics:    make(map[string]map[*Subscription]struct{}),
+		present:   make(map[string]map[string]int),
+		backplane: b,
+	}
+	if b != nil {
+		b.Receive(func(m Message) {
+			// Messages of this hub are already delivered locally
+			if m.Origin != h.ID {
+				h.deliver(m)
+			}
+		})
+	}
+	return h
+}
+
+// Subscribe creates subscription to the topics.
+func (h *Hub) Subscribe(o Options, topics ...string) *Subscription {
+	if o.Buffer <= 0 {
+		o.Buffer = 64
+	}
+	ch := make(chan Message, o.Buffer)
+	s := &Subscription{
+		C:      ch,
+		Key:    o.Key,
+		hub:    h,
+		ch:     ch,
+		policy: o.Policy,
+		topics: make(map[string]struct{}),
+		done:   make(chan struct{}),
+	}
+	s.Join(topics...)
+	return s
+}
+
+// Publish delivers the message to local subscriptions and other hubs of
+// the backplane.
+func (h *Hub) Publish(topic string, data []byte) error {
+	m := Message{Topic: topic, Data: data, Origin: h.ID}
+	h.deliver(m)
+	if h.backplane != nil {
+		return h.backplane.Publish(m)
+	}
+	return nil
+}
+
+// deliver sends the message to local subscriptions of the topic.
+func (h *Hub) deliver(m Message) {
+	h.mu.RLock()
+	subs := make([]*Subscription, 0, len(h.topics[m.Topic]))
+	for s := range h.topics[m.Topic] {
+		subs = append(subs, s)
+	}
+	h.mu.RUnlock()
+	for _, s := range subs {
+		s.send(m)
+	}
+}
+
+// Presence gets sorted keys of subscribers of the topic on this hub only,
+// subscribers of other hubs on the backplane are not included.
+func (h *Hub) Presence(topic string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var keys []string
+	for k := range h.present[topic] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// add adds the subscription to the topic, the lock must be held.
+func (h *Hub) add(s *Subscription, topic string, changes []presence) []presence {
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	if _, ok := h.topics[topic][s]; ok {
+		return changes
+	}
+	h.topics[topic][s] = struct{}{}
+	if s.Key == "" {
+		return changes
+	}
+	if h.present[topic] == nil {
+		h.present[topic] = make(map[string]int)
+	}
+	if h.present[topic][s.Key]++; h.present[topic][s.Key] == 1 {
+		changes = append(changes, presence{topic, s.Key, true})
+	}
+	return changes
+}
+
+// remove removes the subscription from the topic, the lock must be held.
+func (h *Hub) remove(s *Subscription, topic string, changes []presence) []presence {
+	if _, ok := h.topics[topic][s]; !ok {
+		return changes
+	}
+	delete(h.topics[topic], s)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+	if s.Key == "" {
+		return changes
+	}
+	if h.present[topic][s.Key]--; h.present[topic][s.Key] == 0 {
+		delete(h.present[topic], s.Key)
+		if len(h.present[topic]) == 0 {
+			delete(h.present, topic)
+		}
+		changes = append(changes, presence{topic, s.Key, false})
+	}
+	return changes
+}
+
+// notify calls OnPresence with the changes, no lock may be held.
+func (h *Hub) notify(changes []presence) {
+	if h.OnPresence == nil {
+		return
+	}
+	for _, c := range changes {
+		h.OnPresence(c.topic, c.key, c.present)
+	}
+}
+
+// Count gets number of subscriptions of the topic on this hub.
+func (h *Hub) Count(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Topics gets topics with subscriptions on this hub.
+func (h *Hub) Topics() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	topics := make([]string, 0, len(h.topics))
+	for t := range h.topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Subscription receives messages of its topics on C, which is closed once
+// the subscription is closed.
+type Subscription struct {
+	C       <-chan Message
+	Key     string
+	hub     *Hub
+	ch      chan Message
+	policy  Policy
+	mu      sync.Mutex
+	topics  map[string]struct{}
+	closed  bool
+	err     error
+	done    chan struct{}
+	dropped uint64
+}
+
+// Join subscribes to more topics.
+func (s *Subscription) Join(topics ...string) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	h := s.hub
+	var changes []presence
+	h.mu.Lock()
+	for _, t := range topics {
+		s.topics[t] = struct{}{}
+		changes = h.add(s, t, changes)
+	}
+	h.mu.Unlock()
+	s.mu.Unlock()
+	h.notify(changes)
+}
+
+// Leave unsubscribes from the topics.
+func (s *Subscription) Leave(topics ...string) {
+	s.mu.Lock()
+	changes := s.leave(topics)
+	s.mu.Unlock()
+	s.hub.notify(changes)
+}
+
+// leave removes the subscription from topics, the lock must be held.
+func (s *Subscription) leave(topics []string) []presence {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var changes []presence
+	for _, t := range topics {
+		delete(s.topics, t)
+		changes = h.remove(s, t, changes)
+	}
+	return changes
+}
+
+// Close unsubscribes from all topics and closes C.
+func (s *Subscription) Close() {
+	s.close(ErrClosed)
+}
+
+// close closes the subscription with the reason.
+func (s *Subscription) close(err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	topics := make([]string, 0, len(s.topics))
+	for t := range s.topics {
+		topics = append(topics, t)
+	}
+	changes := s.leave(topics)
+	s.closed, s.err = true, err
+	close(s.ch)
+	close(s.done)
+	s.mu.Unlock()
+	s.hub.notify(changes)
+}
+
+// send delivers the message according to the slow consumer policy.
+func (s *Subscription) send(m Message) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	select {
+	case s.ch <- m:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- m:
+		default:
+		}
+	case Disconnect:
+		s.mu.Unlock()
+		atomic.AddUint64(&s.dropped, 1)
+		s.close(ErrSlowConsumer)
+		return
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	s.mu.Unlock()
+}
+
+// Done gets channel closed once the subscription is closed.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err gets the reason the subscription was closed.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped gets number of messages dropped for the full buffer.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
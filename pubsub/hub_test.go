@@ -0,0 +1,105 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHubBackplane(t *testing.T) {
+	b := NewMemoryBackplane()
+	a, c := NewHub(b), NewHub(b)
+	sa := a.Subscribe(Options{}, "room")
+	sc := c.Subscribe(Options{}, "room")
+	defer sa.Close()
+	defer sc.Close()
+	a.Publish("room", []byte("hi"))
+	for _, s := range []*Subscription{sa, sc} {
+		if m := <-s.C; string(m.Data) != "hi" || m.Origin != a.ID {
+			t.Fatalf("got %+v", m)
+		}
+		if len(s.C) != 0 {
+			t.Fatal("message delivered twice")
+		}
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	h := NewHub(nil)
+	drop := h.Subscribe(Options{Buffer: 1, Policy: Drop}, "t")
+	oldest := h.Subscribe(Options{Buffer: 1, Policy: DropOldest}, "t")
+	disconnect := h.Subscribe(Options{Buffer: 1, Policy: Disconnect}, "t")
+	h.Publish("t", []byte("1"))
+	h.Publish("t", []byte("2"))
+	if m := <-drop.C; string(m.Data) != "1" || drop.Dropped() != 1 {
+		t.Fatalf("drop got %q, dropped %d", m.Data, drop.Dropped())
+	}
+	if m := <-oldest.C; string(m.Data) != "2" || oldest.Dropped() != 1 {
+		t.Fatalf("drop oldest got %q, dropped %d", m.Data, oldest.Dropped())
+	}
+	<-disconnect.Done()
+	if disconnect.Err() != ErrSlowConsumer || h.Count("t") != 2 {
+		t.Fatalf("disconnect error %v, count %d", disconnect.Err(), h.Count("t"))
+	}
+}
+
+func TestPresence(t *testing.T) {
+	h := NewHub(nil)
+	var mu sync.Mutex
+	var events []presence
+	h.OnPresence = func(topic, key string, present bool) {
+		mu.Lock()
+		events = append(events, presence{topic, key, present})
+		mu.Unlock()
+	}
+	alice1 := h.Subscribe(Options{Key: "alice"}, "room")
+	alice2 := h.Subscribe(Options{Key: "alice"}, "room", "room")
+	bob := h.Subscribe(Options{Key: "bob"}, "room", "lobby")
+	anon := h.Subscribe(Options{}, "room")
+	defer anon.Close()
+	if keys := h.Presence("room"); !reflect.DeepEqual(keys, []string{"alice", "bob"}) {
+		t.Fatalf("presence %v", keys)
+	}
+	alice1.Close()
+	bob.Leave("room")
+	if keys := h.Presence("room"); !reflect.DeepEqual(keys, []string{"alice"}) {
+		t.Fatalf("presence %v", keys)
+	}
+	alice2.Close()
+	bob.Close()
+	want := []presence{
+		{"room", "alice", true},
+		{"room", "bob", true},
+		{"lobby", "bob", true},
+		{"room", "bob", false},
+		{"room", "alice", false},
+		{"lobby", "bob", false},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events %v", events)
+	}
+	if keys := h.Presence("room"); keys != nil || !reflect.DeepEqual(h.Topics(), []string{"room"}) {
+		t.Fatalf("presence %v, topics %v", keys, h.Topics())
+	}
+}
+
+func TestPresencePublish(t *testing.T) {
+	b := NewMemoryBackplane()
+	a, c := NewHub(b), NewHub(b)
+	a.OnPresence = func(topic, key string, present bool) {
+		if present {
+			a.Publish("presence:"+topic, []byte(key))
+		}
+	}
+	watch := c.Subscribe(Options{}, "presence:room")
+	defer watch.Close()
+	s := a.Subscribe(Options{Key: "alice"}, "room")
+	defer s.Close()
+	if m := <-watch.C; string(m.Data) != "alice" {
+		t.Fatalf("got %+v", m)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package ratelimit limits requests per client with token bucket or sliding
+window algorithms. Clients are identified by IP address, authenticated
+principal, validated API key, or a custom key function. The middleware sets
+the RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers, and
+responds 429 with Retry-After once the limit is exceeded.
+
+Limiter state is kept on a Store. MemoryStore is a sharded in-process store,
+while shared backends implement the Store interface so multiple processes
+enforce the same limit.
+
+	store := ratelimit.NewMemoryStore()
+	global, err := ratelimit.New(store, ratelimit.Limit{
+		Requests: 100,
+		Period:   time.Minute,
+	}, ratelimit.ByIP)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logins, err := ratelimit.New(store, ratelimit.Limit{
+		Requests:  5,
+		Period:    time.Minute,
+		Algorithm: ratelimit.SlidingWindow,
+	}, ratelimit.ByIP)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Middleware(global).Group(func(r *route.Router) {
+		r.Middleware(logins).Post("/login", login)
+	})
+*/
+package ratelimit
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,159 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidLimit represents limit without positive requests and period
+var ErrInvalidLimit = errors.New("ratelimit: Limit requires positive requests and period")
+
+// Algorithm define how requests are counted against the limit.
+type Algorithm int
+
+const (
+	// TokenBucket refills the bucket steadily and allows bursts up to the
+	// bucket size
+	TokenBucket Algorithm = iota
+	// SlidingWindow weights the previous fixed window count by its overlap
+	// with the sliding window
+	SlidingWindow
+)
+
+// Limit define allowed number of requests per period.
+type Limit struct {
+	Requests  int
+	Period    time.Duration
+	Algorithm Algorithm
+	// Burst is the token bucket size, defaults to Requests
+	Burst int
+}
+
+// Result store outcome of a request taken from the limit.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// State store limiter state of a single key. Shared stores that support
+// atomic updates may persist the state and reuse Limit.Apply.
+type State struct {
+	// Tokens left in the bucket
+	Tokens float64
+	// Count of requests in the current window
+	Count int
+	// Previous count of requests in the previous window
+	Previous int
+	// Start is the last refill time or the current window start
+	Start time.Time
+}
+
+// Validate checks whether the limit allows any request.
+func (l Limit) Validate() error {
+	if l.Requests <= 0 || l.Period <= 0 || l.Burst < 0 {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+// Apply takes a request from the state and returns the result. Invalid
+// limits deny every request.
+func (l Limit) Apply(s *State, now time.Time) Result {
+	if l.Validate() != nil {
+		return Result{}
+	}
+	if l.Algorithm == SlidingWindow {
+		return l.slidingWindow(s, now)
+	}
+	return l.tokenBucket(s, now)
+}
+
+// TTL gets duration after which idle state can be discarded.
+func (l Limit) TTL() time.Duration {
+	return 2 * l.Period
+}
+
+// burst gets token bucket size.
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// tokenBucket applies the token bucket algorithm.
+func (l Limit) tokenBucket(s *State, now time.Time) Result {
+	size := float64(l.burst())
+	rate := float64(l.Requests) / float64(l.Period)
+	if s.Start.IsZero() {
+		s.Tokens = size
+	} else if elapsed := now.Sub(s.Start); elapsed > 0 {
+		s.Tokens = math.Min(size, s.Tokens+float64(elapsed)*rate)
+	}
+	s.Start = now
+
+	res := Result{Limit: l.burst()}
+	if s.Tokens >= 1 {
+		s.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - s.Tokens) / rate))
+	}
+	res.Remaining = int(s.Tokens)
+	res.Reset = time.Duration(math.Ceil((size - s.Tokens) / rate))
+	return res
+}
+
+// slidingWindow applies the sliding window counter algorithm.
+func (l Limit) slidingWindow(s *State, now time.Time) Result {
+	start := now.Truncate(l.Period)
+	switch {
+	case s.Start.Equal(start):
+	case s.Start.Add(l.Period).Equal(start):
+		s.Previous, s.Count = s.Count, 0
+	default:
+		s.Previous, s.Count = 0, 0
+	}
+	s.Start = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(l.Period)
+	used := float64(s.Previous)*weight + float64(s.Count)
+
+	res := Result{Limit: l.Requests, Reset: l.Period - elapsed}
+	if used+1 <= float64(l.Requests) {
+		s.Count++
+		used++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.retryAfter(s, elapsed)
+	}
+	res.Remaining = l.Requests - int(math.Ceil(used))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
+
+// retryAfter gets time until the sliding window has room for a request.
+func (l Limit) retryAfter(s *State, elapsed time.Duration) time.Duration {
+	room := float64(l.Requests - 1 - s.Count)
+	if room < 0 || s.Previous == 0 {
+		// Wait for the next window, where current count becomes previous
+		return l.Period - elapsed
+	}
+	// Solve previous * (1 - (elapsed + t) / period) <= room for t
+	t := time.Duration((1-room/float64(s.Previous))*float64(l.Period)) - elapsed
+	if t < time.Millisecond {
+		t = time.Millisecond
+	}
+	return t
+}
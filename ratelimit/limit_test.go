@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func TestTokenBucket(t *testing.T) {
+	l := Limit{Requests: 2, Period: time.Second}
+	s := &State{}
+	now := time.Unix(1000, 0)
+	for i := 0; i < 2; i++ {
+		if res := l.Apply(s, now); !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	res := l.Apply(s, now)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("denied request: %+v", res)
+	}
+	if res := l.Apply(s, now.Add(500*time.Millisecond)); !res.Allowed {
+		t.Fatalf("refilled request: %+v", res)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l := Limit{Requests: 4, Period: time.Minute, Algorithm: SlidingWindow}
+	s := &State{}
+	start := time.Unix(6000, 0)
+	for i := 0; i < 4; i++ {
+		if res := l.Apply(s, start); !res.Allowed {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	if res := l.Apply(s, start.Add(30*time.Second)); res.Allowed || res.RetryAfter != 30*time.Second {
+		t.Fatalf("denied request: %+v", res)
+	}
+	// Half of the previous window overlaps, leaving room for 2 requests
+	next := start.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res := l.Apply(s, next); !res.Allowed {
+			t.Fatalf("next window request %d: %+v", i, res)
+		}
+	}
+	if res := l.Apply(s, next); res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("next window denied request: %+v", res)
+	}
+}
+
+func TestInvalidLimit(t *testing.T) {
+	for _, l := range []Limit{
+		{Requests: 0, Period: time.Second},
+		{Requests: 10, Period: 0},
+		{Requests: 10, Period: time.Second, Burst: -1},
+	} {
+		if _, err := New(NewMemoryStore(), l, ByIP); err != ErrInvalidLimit {
+			t.Errorf("%+v: got %v, want ErrInvalidLimit", l, err)
+		}
+		if res := l.Apply(&State{}, time.Now()); res.Allowed || res.RetryAfter != 0 || res.Reset != 0 {
+			t.Errorf("%+v: applied %+v", l, res)
+		}
+	}
+}
+
+func TestLimiterHeaders(t *testing.T) {
+	m, err := New(NewMemoryStore(), Limit{Requests: 100, Period: time.Second, Burst: 1}, ByIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := route.MiddlewareRunner{
+		Stack:   []route.Middleware{m},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "100;w=1" {
+		t.Fatalf("got %d %v", w.Code, w.Header())
+	}
+	// Retry-After of 10ms is rounded up to a whole second
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 429 || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("got %d with Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestByHeader(t *testing.T) {
+	key := ByHeader("X-API-Key", func(v string) bool { return v == "good" })
+	tests := []struct {
+		header, want string
+	}{
+		{"good", "header:X-API-Key:good"},
+		{"forged", "ip:192.0.2.1"},
+		{"", "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("X-API-Key", tt.header)
+		}
+		if got := key(r); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.header, got, tt.want)
+		}
+	}
+	// Without validation every request falls back to the IP address
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-API-Key", "good")
+	if got := ByHeader("X-API-Key", nil)(r); got != "ip:192.0.2.1" {
+		t.Fatalf("got %s", got)
+	}
+}
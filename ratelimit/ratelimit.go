@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mandala/omnibus/realip"
+	"github.com/mandala/omnibus/route"
+)
+
+// KeyFunc identifies the client of a request. Requests with empty key are
+// not limited.
+type KeyFunc func(*http.Request) string
+
+// ByIP identifies clients by IP address, resolved by realip middleware
+// behind trusted proxies.
+func ByIP(r *http.Request) string {
+	return "ip:" + realip.ClientIP(r)
+}
+
+// ByPrincipal identifies clients by authenticated principal, falling back
+// to remote IP address for anonymous requests.
+func ByPrincipal(r *http.Request) string {
+	if p := route.GetPrincipal(r); p != nil {
+		return "principal:" + p.Scheme + ":" + p.ID
+	}
+	return ByIP(r)
+}
+
+// ByHeader identifies clients by the header value such as API key, falling
+// back to remote IP address for requests without the header or with value
+// rejected by the valid function. Raw header values are chosen by the
+// client, so unless the value is validated, sending a new value on every
+// request gets a fresh limit. Prefer ByPrincipal after authentication
+// middleware.
+func ByHeader(name string, valid func(string) bool) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" && valid != nil && valid(v) {
+			return "header:" + name + ":" + v
+		}
+		return ByIP(r)
+	}
+}
+
+// limiterID generates default limiter names
+var limiterID int64
+
+// Limiter is the middleware that limits requests per client.
+type Limiter struct {
+	Limit Limit
+	Store Store
+	Key   KeyFunc
+	// Name namespaces keys of the limiter on the store, defaults to unique
+	// name per limiter. Limiters with the same name share their counts.
+	Name string
+	// FailClosed rejects requests when the store fails, by default
+	// requests are allowed
+	FailClosed bool
+	// ErrorHandler handles limited requests, defaults to 429 response
+	ErrorHandler http.Handler
+}
+
+// New creates rate limiter middleware, the limit must allow requests.
+func New(store Store, l Limit, key KeyFunc) (*Limiter, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return &Limiter{
+		Limit: l,
+		Store: store,
+		Key:   key,
+		Name:  fmt.Sprintf("rl%d", atomic.AddInt64(&limiterID, 1)),
+	}, nil
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Limiter) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	key := m.Key
+	if key == nil {
+		key = ByIP
+	}
+	k := key(r)
+	if k == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	res, err := m.Store.Take(r.Context(), m.Name+":"+k, m.Limit)
+	if err != nil {
+		if m.FailClosed {
+			http.Error(w, http.StatusText(503), 503)
+			return
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+	m.setHeaders(w.Header(), res)
+	if !res.Allowed {
+		// Clients retrying immediately would be denied again
+		retry := res.RetryAfter
+		if retry < time.Second {
+			retry = time.Second
+		}
+		w.Header().Set("Retry-After", seconds(retry))
+		if m.ErrorHandler != nil {
+			m.ErrorHandler.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(429), 429)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// setHeaders sets RateLimit headers unless a more restrictive limiter
+// already set them.
+func (m *Limiter) setHeaders(h http.Header, res Result) {
+	if v := h.Get("RateLimit-Remaining"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n < res.Remaining {
+			return
+		}
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", m.Limit.Requests, seconds(m.Limit.Period)))
+}
+
+// seconds formats duration as whole seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Store keeps limiter state of keys. Take must apply the limit atomically
+// so concurrent requests of the same key are counted once each.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// shardCount is number of independently locked memory store shards
+const shardCount = 64
+
+// MemoryStore is sharded in-process limiter store.
+type MemoryStore struct {
+	shards [shardCount]shard
+}
+
+// shard store limiter state of a subset of keys
+type shard struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+}
+
+// entry store limiter state with its expiration
+type entry struct {
+	State
+	expires time.Time
+}
+
+// NewMemoryStore creates empty memory store.
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{}
+	for i := range m.shards {
+		m.shards[i].entries = make(map[string]*entry)
+	}
+	return m
+}
+
+// Take implements ratelimit.Store interface.
+func (m *MemoryStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	s := &m.shards[h.Sum32()%shardCount]
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.swept) > time.Minute {
+		s.sweep(now)
+	}
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{}
+		s.entries[key] = e
+	}
+	res := l.Apply(&e.State, now)
+	e.expires = now.Add(l.TTL())
+	return res, nil
+}
+
+// Len gets number of keys on the store.
+func (m *MemoryStore) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		n += len(s.entries)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// sweep removes expired entries of the shard.
+func (s *shard) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.swept = now
+}
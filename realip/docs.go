@@ -0,0 +1,23 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package realip resolves the real client address of requests behind trusted
+proxies. The resolver middleware reads either the X-Forwarded-* headers or
+the Forwarded header, as chosen by the Source option, only from trusted
+peers, and stores the client IP, scheme, and host on the request context. The request URL scheme and host are updated, so
+absolute URLs built by route package point to the original address.
+
+	resolver, err := realip.New(realip.Options{
+		TrustedProxies: []string{"10.0.0.0/8", "127.0.0.1/32"},
+		Source:         realip.Forwarded,
+	})
+	router.Middleware(resolver)
+
+Load balancers that speak PROXY protocol version 1 or 2 are supported by
+wrapping the listener with Listener, or by serve.Options.ProxyProtocol.
+*/
+package realip
+
+// This file is intentionally left blank for Godoc documentation.
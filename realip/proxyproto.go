@@ -0,0 +1,196 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package realip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidHeader represents malformed PROXY protocol header
+var ErrInvalidHeader = errors.New("realip: Invalid PROXY protocol header")
+
+// PROXY protocol version 2 signature
+var signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxHeaderV2 limits length of version 2 header with its TLVs
+const maxHeaderV2 = 4096
+
+// Listener accepts connections with PROXY protocol header sent by trusted
+// peers. The header is optional, connections without the header keep
+// their peer address. Connections of untrusted peers are never parsed.
+type Listener struct {
+	net.Listener
+	Trusted Trusted
+	// Timeout limits time to read the header, defaults to 10 seconds
+	Timeout time.Duration
+}
+
+// NewListener wraps the listener with PROXY protocol support for the
+// trusted CIDRs.
+func NewListener(ln net.Listener, trusted []string) (*Listener, error) {
+	t, err := ParseTrusted(trusted)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: ln, Trusted: t}, nil
+}
+
+// Accept implements net.Listener interface.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.Trusted.Contains(hostIP(conn.RemoteAddr().String())) {
+		return conn, nil
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &proxyConn{Conn: conn, r: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// proxyConn parses the header lazily on first read or address lookup, so
+// slow peers do not block the accept loop
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	once    sync.Once
+	remote  net.Addr
+	local   net.Addr
+	err     error
+}
+
+// Read implements net.Conn interface.
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.parse)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr gets client address sent in the header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.parse)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr gets destination address sent in the header.
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.once.Do(c.parse)
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// parse reads PROXY protocol header if present.
+func (c *proxyConn) parse() {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+	b, err := c.r.Peek(1)
+	if err != nil {
+		c.err = err
+		return
+	}
+	switch b[0] {
+	case 'P':
+		c.err = c.parseV1()
+	case '\r':
+		c.err = c.parseV2()
+	}
+}
+
+// parseV1 parses text header such as "PROXY TCP4 src dst sport dport".
+func (c *proxyConn) parseV1() error {
+	b, err := c.r.Peek(6)
+	if err != nil || string(b) != "PROXY " {
+		// Not a header, leave the data to the reader
+		return nil
+	}
+	var line []byte
+	for len(line) < 107 {
+		ch, err := c.r.ReadByte()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		line = append(line, ch)
+		if ch == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return ErrInvalidHeader
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return ErrInvalidHeader
+	}
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	sport, err1 := strconv.ParseUint(fields[4], 10, 16)
+	dport, err2 := strconv.ParseUint(fields[5], 10, 16)
+	if src == nil || dst == nil || err1 != nil || err2 != nil {
+		return ErrInvalidHeader
+	}
+	c.remote = &net.TCPAddr{IP: src, Port: int(sport)}
+	c.local = &net.TCPAddr{IP: dst, Port: int(dport)}
+	return nil
+}
+
+// parseV2 parses binary header.
+func (c *proxyConn) parseV2() error {
+	b, err := c.r.Peek(16)
+	if err != nil || !bytes.Equal(b[:12], signature) {
+		return nil
+	}
+	n := 16 + int(binary.BigEndian.Uint16(b[14:16]))
+	if b[12]>>4 != 2 || n > maxHeaderV2 {
+		return ErrInvalidHeader
+	}
+	header := make([]byte, n)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return err
+	}
+	if header[12]&0x0F == 0 {
+		// LOCAL command, such as health checks of the proxy itself
+		return nil
+	}
+	addr := header[16:]
+	switch header[13] >> 4 {
+	case 1:
+		if len(addr) < 12 {
+			return ErrInvalidHeader
+		}
+		c.remote = &net.TCPAddr{IP: net.IP(addr[0:4]), Port: int(binary.BigEndian.Uint16(addr[8:10]))}
+		c.local = &net.TCPAddr{IP: net.IP(addr[4:8]), Port: int(binary.BigEndian.Uint16(addr[10:12]))}
+	case 2:
+		if len(addr) < 36 {
+			return ErrInvalidHeader
+		}
+		c.remote = &net.TCPAddr{IP: net.IP(addr[0:16]), Port: int(binary.BigEndian.Uint16(addr[32:34]))}
+		c.local = &net.TCPAddr{IP: net.IP(addr[16:32]), Port: int(binary.BigEndian.Uint16(addr[34:36]))}
+	}
+	return nil
+}
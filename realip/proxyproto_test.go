@@ -0,0 +1,122 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package realip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipeConn sends the bytes on a pipe and gets the receiving proxy
+// connection.
+func pipeConn(data []byte) *proxyConn {
+	client, server := net.Pipe()
+	go func() {
+		client.Write(data)
+		client.Close()
+	}()
+	return &proxyConn{Conn: server, r: bufio.NewReader(server), timeout: time.Second}
+}
+
+// headerV2 builds version 2 header of the command, family, and address.
+func headerV2(command, family byte, addr []byte, length int) []byte {
+	b := append([]byte(nil), signature...)
+	b = append(b, 0x20|command, family<<4|1, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(length))
+	return append(b, addr...)
+}
+
+func TestProxyProtocol(t *testing.T) {
+	v4 := []byte{198, 51, 100, 1, 10, 0, 0, 1, 0x30, 0x39, 0x01, 0xBB}
+	v6 := make([]byte, 36)
+	copy(v6, net.ParseIP("2001:db8::1"))
+	copy(v6[16:], net.ParseIP("2001:db8::2"))
+	binary.BigEndian.PutUint16(v6[32:], 12345)
+	binary.BigEndian.PutUint16(v6[34:], 443)
+	tests := []struct {
+		name   string
+		data   []byte
+		remote string
+		local  string
+		body   string
+		err    bool
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 443\r\nGET"), "198.51.100.1:12345", "10.0.0.1:443", "GET", false},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 12345 443\r\nGET"), "[2001:db8::1]:12345", "[2001:db8::2]:443", "GET", false},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nGET"), "", "", "GET", false},
+		{"v1 bad port", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 99999 443\r\nGET"), "", "", "", true},
+		{"v1 bad address", []byte("PROXY TCP4 example.com 10.0.0.1 1 443\r\nGET"), "", "", "", true},
+		{"v1 missing crlf", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 443\nGET"), "", "", "", true},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", "", "", true},
+		{"v1 truncated", []byte("PROXY TCP4 198.51.100.1"), "", "", "", true},
+		{"no header", []byte("GET / HTTP/1.1\r\n"), "", "", "GET / HTTP/1.1\r\n", false},
+		{"v2 tcp4", append(headerV2(1, 1, v4, 12), "GET"...), "198.51.100.1:12345", "10.0.0.1:443", "GET", false},
+		{"v2 tcp6", append(headerV2(1, 2, v6, 36), "GET"...), "[2001:db8::1]:12345", "[2001:db8::2]:443", "GET", false},
+		{"v2 tlv", append(headerV2(1, 1, append(v4, 0x04, 0, 1, 'x'), 16), "GET"...), "198.51.100.1:12345", "10.0.0.1:443", "GET", false},
+		{"v2 local", append(headerV2(0, 0, nil, 0), "GET"...), "", "", "GET", false},
+		{"v2 short address", headerV2(1, 1, v4[:8], 8), "", "", "", true},
+		{"v2 truncated", headerV2(1, 1, v4[:8], 12), "", "", "", true},
+		{"v2 oversized", headerV2(1, 1, v4, 65535), "", "", "", true},
+		{"v2 bad version", append(append([]byte(nil), signature...), 0x11, 0x11, 0, 0), "", "", "", true},
+	}
+	for _, tt := range tests {
+		c := pipeConn(tt.data)
+		remote, local := c.RemoteAddr().String(), c.LocalAddr().String()
+		body, err := io.ReadAll(c)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: no error, read %q", tt.name, body)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.remote == "" {
+			tt.remote, tt.local = "pipe", "pipe"
+		}
+		if remote != tt.remote || local != tt.local || string(body) != tt.body {
+			t.Errorf("%s: got %s %s %q", tt.name, remote, local, body)
+		}
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	for _, trusted := range []string{"127.0.0.1", "10.0.0.0/8"} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl, err := NewListener(ln, []string{trusted})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			c, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				return
+			}
+			c.Write([]byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 443\r\n"))
+			c.Close()
+		}()
+		c, err := pl.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Headers of untrusted peers are left unparsed
+		remote := hostIP(c.RemoteAddr().String()).String()
+		if trusted == "127.0.0.1" && remote != "198.51.100.1" || trusted != "127.0.0.1" && remote != "127.0.0.1" {
+			t.Errorf("trusted %s: got remote %s", trusted, remote)
+		}
+		c.Close()
+		pl.Close()
+	}
+}
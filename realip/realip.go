@@ -0,0 +1,256 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package realip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	infoKey contextKey = iota
+)
+
+// Trusted store trusted proxy networks
+type Trusted []*net.IPNet
+
+// ParseTrusted parses trusted proxy CIDRs or single IP addresses.
+func ParseTrusted(cidrs []string) (Trusted, error) {
+	var t Trusted
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			if ip := net.ParseIP(c); ip != nil && ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, n)
+	}
+	return t, nil
+}
+
+// Contains checks whether the IP address is trusted.
+func (t Trusted) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range t {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Source define headers carrying forwarded client information
+type Source int
+
+const (
+	// XForwarded reads X-Forwarded-For, X-Forwarded-Proto, and
+	// X-Forwarded-Host headers
+	XForwarded Source = iota
+	// Forwarded reads RFC 7239 Forwarded header
+	Forwarded
+)
+
+// Options store resolver configurations
+type Options struct {
+	// TrustedProxies lists CIDRs of proxies allowed to forward client
+	// information
+	TrustedProxies []string
+	// Source is the headers set by the trusted proxies, defaults to
+	// X-Forwarded-* headers. Headers of the other source are ignored, as
+	// proxies pass them from the client untouched.
+	Source Source
+	// Header reads client IP from single value header such as X-Real-IP
+	// instead of the Source headers
+	Header string
+}
+
+// Info store resolved client information of the request
+type Info struct {
+	IP     net.IP
+	Scheme string
+	Host   string
+	// Proxy is the peer address when the request came through trusted
+	// proxies
+	Proxy string
+}
+
+// Resolver is the middleware that resolves client information from
+// headers sent by trusted proxies.
+type Resolver struct {
+	Options
+	trusted Trusted
+}
+
+// New creates resolver middleware.
+func New(o Options) (*Resolver, error) {
+	t, err := ParseTrusted(o.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{Options: o, trusted: t}, nil
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Resolver) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	info := m.Resolve(r)
+	r = r.WithContext(context.WithValue(r.Context(), infoKey, info))
+	if info.Proxy != "" {
+		u := *r.URL
+		u.Scheme = info.Scheme
+		r.URL, r.Host = &u, info.Host
+	}
+	next.ServeHTTP(w, r)
+}
+
+// Resolve gets client information of the request.
+func (m *Resolver) Resolve(r *http.Request) *Info {
+	info := &Info{IP: hostIP(r.RemoteAddr), Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+	if !m.trusted.Contains(info.IP) {
+		return info
+	}
+	var hops []hop
+	switch {
+	case m.Header != "":
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(m.Header))); ip != nil {
+			hops = []hop{{ip: ip}}
+		}
+	case m.Source == Forwarded:
+		hops = parseForwarded(r.Header.Values("Forwarded"))
+	default:
+		hops = parseXForwarded(r.Header)
+	}
+	if len(hops) == 0 {
+		return info
+	}
+	// Walk from the nearest hop and stop at the first untrusted address
+	i := len(hops) - 1
+	for i > 0 && m.trusted.Contains(hops[i].ip) {
+		i--
+	}
+	if hops[i].ip == nil {
+		return info
+	}
+	info.Proxy = r.RemoteAddr
+	info.IP = hops[i].ip
+	if proto := strings.ToLower(hops[i].proto); proto == "http" || proto == "https" {
+		info.Scheme = proto
+	}
+	if hops[i].host != "" {
+		info.Host = hops[i].host
+	}
+	return info
+}
+
+// hop store forwarding information of a single proxy hop
+type hop struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+// parseForwarded parses RFC 7239 Forwarded header values.
+func parseForwarded(values []string) []hop {
+	var hops []hop
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			var h hop
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					h.ip = nodeIP(v)
+				case "proto":
+					h.proto = v
+				case "host":
+					h.host = v
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// parseXForwarded parses X-Forwarded-For, X-Forwarded-Proto, and
+// X-Forwarded-Host headers. Scheme and host of all hops are taken from the
+// last value, set by the nearest proxy.
+func parseXForwarded(h http.Header) []hop {
+	var hops []hop
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(v, ",") {
+			hops = append(hops, hop{ip: nodeIP(strings.TrimSpace(addr))})
+		}
+	}
+	proto, host := lastValue(h, "X-Forwarded-Proto"), lastValue(h, "X-Forwarded-Host")
+	for i := range hops {
+		hops[i].proto, hops[i].host = proto, host
+	}
+	return hops
+}
+
+// lastValue gets the last comma separated value of the header.
+func lastValue(h http.Header, key string) string {
+	values := h.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// nodeIP parses IP address of a node with optional port and brackets.
+func nodeIP(node string) net.IP {
+	if ip := net.ParseIP(node); ip != nil {
+		return ip
+	}
+	return hostIP(node)
+}
+
+// hostIP parses IP address of host and port pair.
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = strings.Trim(addr, "[]")
+	}
+	return net.ParseIP(host)
+}
+
+// GetInfo gets resolved client information of the request.
+func GetInfo(r *http.Request) *Info {
+	info, _ := r.Context().Value(infoKey).(*Info)
+	return info
+}
+
+// ClientIP gets resolved client IP address of the request, falling back to
+// the remote address.
+func ClientIP(r *http.Request) string {
+	if info := GetInfo(r); info != nil && info.IP != nil {
+		return info.IP.String()
+	}
+	if ip := hostIP(r.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
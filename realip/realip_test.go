@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func TestResolve(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.0.2.1"}
+	tests := []struct {
+		name    string
+		options Options
+		remote  string
+		header  http.Header
+		ip      string
+		scheme  string
+		host    string
+	}{
+		{
+			name:   "untrusted peer",
+			remote: "203.0.113.9:1234",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			ip:     "203.0.113.9", scheme: "http", host: "example.com",
+		},
+		{
+			name:   "x-forwarded",
+			remote: "10.0.0.1:1234",
+			header: http.Header{
+				"X-Forwarded-For":   {"198.51.100.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"www.example.com"},
+			},
+			ip: "198.51.100.1", scheme: "https", host: "www.example.com",
+		},
+		{
+			name:   "trusted hops are skipped",
+			remote: "10.0.0.1:1234",
+			header: http.Header{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1", "10.0.0.2, 192.0.2.1"}},
+			ip:     "198.51.100.1", scheme: "http", host: "example.com",
+		},
+		{
+			name:   "all hops trusted",
+			remote: "10.0.0.1:1234",
+			header: http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			ip:     "10.0.0.3", scheme: "http", host: "example.com",
+		},
+		{
+			name:   "client forwarded header is ignored",
+			remote: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=6.6.6.6;proto=https;host=evil.example"},
+				"X-Forwarded-For": {"198.51.100.1"},
+			},
+			ip: "198.51.100.1", scheme: "http", host: "example.com",
+		},
+		{
+			name:    "forwarded",
+			options: Options{Source: Forwarded},
+			remote:  "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {`for=6.6.6.6, for="[2001:db8::1]:443";proto=https;host=www.example.com`, "for=10.0.0.2"},
+				"X-Forwarded-For": {"198.51.100.1"},
+			},
+			ip: "2001:db8::1", scheme: "https", host: "www.example.com",
+		},
+		{
+			name:    "forwarded without header",
+			options: Options{Source: Forwarded},
+			remote:  "10.0.0.1:1234",
+			header:  http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			ip:      "10.0.0.1", scheme: "http", host: "example.com",
+		},
+		{
+			name:    "invalid forwarded node",
+			options: Options{Source: Forwarded},
+			remote:  "10.0.0.1:1234",
+			header:  http.Header{"Forwarded": {"for=unknown"}},
+			ip:      "10.0.0.1", scheme: "http", host: "example.com",
+		},
+		{
+			name:    "single value header",
+			options: Options{Header: "X-Real-IP"},
+			remote:  "10.0.0.1:1234",
+			header:  http.Header{"X-Real-Ip": {" 198.51.100.7 "}, "X-Forwarded-For": {"198.51.100.1"}},
+			ip:      "198.51.100.7", scheme: "http", host: "example.com",
+		},
+	}
+	for _, tt := range tests {
+		tt.options.TrustedProxies = trusted
+		m, err := New(tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		r.RemoteAddr, r.Header = tt.remote, tt.header
+		info := m.Resolve(r)
+		if info.IP.String() != tt.ip || info.Scheme != tt.scheme || info.Host != tt.host {
+			t.Errorf("%s: got %s %s %s, want %s %s %s", tt.name, info.IP, info.Scheme, info.Host, tt.ip, tt.scheme, tt.host)
+		}
+	}
+}
+
+func TestResolverMiddleware(t *testing.T) {
+	m, err := New(Options{TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ip, scheme, host string
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{m},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, scheme, host = ClientIP(r), route.RequestScheme(r), r.Host
+		}),
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.2.3:80"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "www.example.com")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if ip != "198.51.100.1" || scheme != "https" || host != "www.example.com" {
+		t.Fatalf("got %s %s %s", ip, scheme, host)
+	}
+	if _, err := New(Options{TrustedProxies: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package rewrite applies declarative redirect and rewrite rules, such as the
+legacy URL tables of site migrations. Rules match the exact path, a path
+prefix, or a regular expression with capture substitution, optionally
+limited to a host. Matching requests are redirected, or internally
+rewritten before routing.
+
+Rules are given as Go slice or loaded from JSON file, and can be reloaded at
+runtime while serving requests.
+
+	[
+		{"exact": "/about-us.php", "target": "/about"},
+		{"prefix": "/blog", "target": "https://blog.example.com"},
+		{"regex": "^/products/(?P<id>[0-9]+)\\.html$", "target": "/shop/${id}"},
+		{"prefix": "/api/v1", "target": "/api/v2", "rewrite": true},
+		{"host": "old.example.com", "target": "https://www.example.com"}
+	]
+
+The table runs as middleware in front of the router, or as fallback so that
+only requests without matching route are looked up.
+
+	table, err := rewrite.LoadFile("redirects.json")
+	router.NotFoundHandler = table.Fallback(router, route.NotFoundHandler)
+
+Table.Hits reports how many requests each rule handled, telling which legacy
+URLs are still in use.
+*/
+package rewrite
+
+// This file is intentionally left blank for Godoc documentation.
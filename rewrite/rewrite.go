@@ -0,0 +1,325 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package rewrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoTarget represents rule without target
+var ErrNoTarget = errors.New("rewrite: Rule target is empty")
+
+// ErrRewriteURL represents rewrite rule targeting absolute URL
+var ErrRewriteURL = errors.New("rewrite: Rewrite rule target must be a path")
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	rewrittenKey contextKey = iota
+)
+
+// Rule define a redirect or rewrite rule. Rules without path matcher match
+// all paths of the host, as prefix "/".
+type Rule struct {
+	// Host limits the rule to the host, "*.example.com" matches subdomains
+	Host   string `json:"host,omitempty"`
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	// Target is the destination path or URL. Prefix rules append the path
+	// remainder, and regex rules expand $1 or ${name} captures.
+	Target string `json:"target"`
+	// Status of the redirect, defaults to 301 for GET and HEAD requests and
+	// 308 for other methods
+	Status int `json:"status,omitempty"`
+	// Rewrite changes the request path internally instead of redirecting
+	Rewrite bool `json:"rewrite,omitempty"`
+}
+
+// Hit store number of requests handled by a rule
+type Hit struct {
+	Rule  Rule   `json:"rule"`
+	Count uint64 `json:"count"`
+}
+
+// compiled store rule with its matcher and hit counter
+type compiled struct {
+	Rule
+	re      *regexp.Regexp
+	indexed bool
+	hits    uint64
+}
+
+// Table store redirect and rewrite rules. Exact rules take precedence, then
+// prefix and regex rules are tried in order.
+type Table struct {
+	mu    sync.RWMutex
+	rules []*compiled
+	exact map[string]*compiled
+}
+
+// New creates table with the rules.
+func New(rules []Rule) (*Table, error) {
+	t := &Table{}
+	if err := t.Reload(rules); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadFile creates table with rules of the JSON file.
+func LoadFile(name string) (*Table, error) {
+	t := &Table{}
+	if err := t.ReloadFile(name); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Reload replaces rules of the table, resetting hit counts.
+func (t *Table) Reload(rules []Rule) error {
+	var list []*compiled
+	exact := make(map[string]*compiled)
+	for i, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			return fmt.Errorf("rewrite: Rule %d: %w", i, err)
+		}
+		list = append(list, c)
+		if c.Exact != "" && !strings.HasPrefix(c.Host, "*.") {
+			c.indexed = true
+			key := strings.ToLower(c.Host) + c.Exact
+			if _, ok := exact[key]; !ok {
+				exact[key] = c
+			}
+		}
+	}
+	t.mu.Lock()
+	t.rules, t.exact = list, exact
+	t.mu.Unlock()
+	return nil
+}
+
+// ReloadFile replaces rules of the table with rules of the JSON file.
+func (t *Table) ReloadFile(name string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return err
+	}
+	return t.Reload(rules)
+}
+
+// compile validates the rule and compiles its matcher.
+func compile(rule Rule) (*compiled, error) {
+	if rule.Target == "" {
+		return nil, ErrNoTarget
+	}
+	if rule.Rewrite && !strings.HasPrefix(rule.Target, "/") {
+		return nil, ErrRewriteURL
+	}
+	if rule.Exact == "" && rule.Prefix == "" && rule.Regex == "" {
+		rule.Prefix = "/"
+	}
+	c := &compiled{Rule: rule}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, err
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// Hits gets hit count of each rule.
+func (t *Table) Hits() []Hit {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	hits := make([]Hit, len(t.rules))
+	for i, c := range t.rules {
+		hits[i] = Hit{Rule: c.Rule, Count: atomic.LoadUint64(&c.hits)}
+	}
+	return hits
+}
+
+// Match gets the rule matching the request and its expanded target.
+func (t *Table) Match(r *http.Request) (Rule, string, bool) {
+	host := strings.ToLower(r.Host)
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(host, "[") {
+		host = h
+	}
+	p := r.URL.Path
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	// Exact rules of the host take precedence over rules of any host
+	for _, key := range []string{host + p, p} {
+		if c, ok := t.exact[key]; ok {
+			atomic.AddUint64(&c.hits, 1)
+			return c.Rule, c.Target, true
+		}
+	}
+	for _, c := range t.rules {
+		if c.indexed || !matchHost(c.Host, host) {
+			continue
+		}
+		if target, ok := c.match(p); ok {
+			atomic.AddUint64(&c.hits, 1)
+			return c.Rule, target, true
+		}
+	}
+	return Rule{}, "", false
+}
+
+// match matches the path against the rule.
+func (c *compiled) match(p string) (string, bool) {
+	if c.Exact != "" {
+		return c.Target, p == c.Exact
+	}
+	if c.re != nil {
+		m := c.re.FindStringSubmatchIndex(p)
+		if m == nil {
+			return "", false
+		}
+		return cleanTarget(string(c.re.ExpandString(nil, c.Target, p, m))), true
+	}
+	prefix := c.Prefix
+	if p != prefix && !strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/") {
+		return "", false
+	}
+	rest := strings.TrimPrefix(p, strings.TrimSuffix(prefix, "/"))
+	// Append the remainder to the target path, before its query
+	target, q, ok := strings.Cut(c.Target, "?")
+	target = strings.TrimSuffix(target, "/") + rest
+	if target == "" {
+		target = "/"
+	}
+	if ok {
+		target += "?" + q
+	}
+	return cleanTarget(target), true
+}
+
+// cleanTarget cleans path of the expanded target. Leading slashes and
+// backslashes are collapsed, since browsers take "//host" and "/\host" as
+// another host.
+func cleanTarget(target string) string {
+	p, q, ok := strings.Cut(target, "?")
+	if !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "\\") {
+		// Absolute URL or relative path
+		return target
+	}
+	trailing := strings.HasSuffix(p, "/")
+	p = path.Clean("/" + strings.TrimLeft(p, "/\\"))
+	if trailing && p != "/" {
+		p += "/"
+	}
+	if ok {
+		p += "?" + q
+	}
+	return p
+}
+
+// matchHost checks the request host against the rule host.
+func matchHost(pattern, host string) bool {
+	if pattern == "" {
+		return true
+	}
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (t *Table) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rule, target, ok := t.Match(r)
+	switch {
+	case !ok:
+		next.ServeHTTP(w, r)
+	case rule.Rewrite:
+		next.ServeHTTP(w, rewrite(r, target))
+	default:
+		redirect(w, r, rule, target)
+	}
+}
+
+// Fallback creates handler that looks up requests not found by the router
+// before running the not found handler. Rewritten requests are dispatched
+// to the router once more.
+func (t *Table) Fallback(router, notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Do not look up rewritten requests again to prevent loops
+		if r.Context().Value(rewrittenKey) != nil {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+		rule, target, ok := t.Match(r)
+		switch {
+		case !ok:
+			notFound.ServeHTTP(w, r)
+		case rule.Rewrite:
+			router.ServeHTTP(w, rewrite(r, target))
+		default:
+			redirect(w, r, rule, target)
+		}
+	})
+}
+
+// rewrite gets shallow copy of the request with the target path and query.
+func rewrite(r *http.Request, target string) *http.Request {
+	r = r.WithContext(context.WithValue(r.Context(), rewrittenKey, true))
+	u := *r.URL
+	p, q, ok := strings.Cut(target, "?")
+	u.Path, u.RawPath = p, ""
+	if ok {
+		if u.RawQuery != "" {
+			q += "&" + u.RawQuery
+		}
+		u.RawQuery = q
+	}
+	r.URL = &u
+	r.RequestURI = u.RequestURI()
+	return r
+}
+
+// redirect redirects the request to the target, keeping its query.
+func redirect(w http.ResponseWriter, r *http.Request, rule Rule, target string) {
+	if r.URL.RawQuery != "" {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.URL.RawQuery
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(status(r, rule))
+}
+
+// status gets redirect status of the rule.
+func status(r *http.Request, rule Rule) int {
+	if rule.Status > 0 {
+		return rule.Status
+	}
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return 301
+	}
+	return 308
+}
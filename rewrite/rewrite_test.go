@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package rewrite
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// serve runs the request through table middleware, the handler echoes the
+// request URI.
+func serve(t *Table, method, url string) *httptest.ResponseRecorder {
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{t},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.RequestURI()))
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+	return w
+}
+
+func TestTable(t *testing.T) {
+	table, err := New([]Rule{
+		{Exact: "/about-us.php", Target: "/about"},
+		{Host: "shop.example.com", Exact: "/about-us.php", Target: "/shop/about"},
+		{Prefix: "/blog", Target: "https://blog.example.com"},
+		{Regex: `^/products/(?P<id>[0-9]+)\.html$`, Target: "/shop/${id}"},
+		{Prefix: "/api/v1", Target: "/api/v2?v=1", Rewrite: true},
+		{Prefix: "/moved", Target: "/new/", Status: 302},
+		{Prefix: "/old", Target: "/"},
+		{Regex: `^/go(.*)$`, Target: "$1"},
+		{Regex: `^/back(.*)$`, Target: `/\$1`},
+		{Host: "*.legacy.example.com", Target: "https://www.example.com/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		status   int
+		location string
+		body     string
+	}{
+		{"exact", "GET", "http://example.com/about-us.php?a=1", 301, "/about?a=1", ""},
+		{"exact host", "GET", "http://shop.example.com:8080/about-us.php", 301, "/shop/about", ""},
+		{"prefix url", "GET", "http://example.com/blog/2017/post", 301, "https://blog.example.com/2017/post", ""},
+		{"prefix root", "POST", "http://example.com/blog", 308, "https://blog.example.com", ""},
+		{"prefix boundary", "GET", "http://example.com/blogger", 200, "", "/blogger"},
+		{"regex", "GET", "http://example.com/products/42.html", 301, "/shop/42", ""},
+		{"regex mismatch", "GET", "http://example.com/products/x.html", 200, "", "/products/x.html"},
+		{"rewrite", "GET", "http://example.com/api/v1/users?id=1", 200, "", "/api/v2/users?v=1&id=1"},
+		{"status", "GET", "http://example.com/moved/a/", 302, "/new/a/", ""},
+		{"wildcard host", "GET", "http://a.legacy.example.com/x", 301, "https://www.example.com/x", ""},
+		{"wildcard apex", "GET", "http://legacy.example.com/x", 200, "", "/x"},
+		{"open redirect prefix", "GET", "http://example.com/old//evil.com", 301, "/evil.com", ""},
+		{"open redirect backslash", "GET", `http://example.com/old/\evil.com`, 301, "/evil.com", ""},
+		{"open redirect regex", "GET", "http://example.com/go//evil.com/a", 301, "/evil.com/a", ""},
+		{"open redirect regex backslash", "GET", "http://example.com/back/evil.com", 301, "/evil.com", ""},
+		{"prefix to root", "GET", "http://example.com/old", 301, "/", ""},
+		{"dot segments", "GET", "http://example.com/old/a/../b/", 301, "/b/", ""},
+	}
+	for _, tt := range tests {
+		w := serve(table, tt.method, tt.url)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location || w.Body.String() != tt.body {
+			t.Errorf("%s: got %d %q %q, want %d %q %q", tt.name, w.Code, w.Header().Get("Location"), w.Body.String(), tt.status, tt.location, tt.body)
+		}
+	}
+	hits := table.Hits()
+	counts := []uint64{1, 1, 2, 1, 1, 1, 4, 1, 1, 1}
+	for i, h := range hits {
+		if h.Count != counts[i] {
+			t.Errorf("rule %d: got %d hits, want %d", i, h.Count, counts[i])
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	table, err := New([]Rule{
+		{Exact: "/old", Target: "/new", Rewrite: true},
+		{Exact: "/loop", Target: "/loop", Rewrite: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	})
+	var router http.Handler
+	router = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/new" {
+			w.Write([]byte("new"))
+			return
+		}
+		table.Fallback(router, notFound).ServeHTTP(w, r)
+	})
+	for url, status := range map[string]int{"/new": 200, "/old": 200, "/loop": 404, "/x": 404} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != status {
+			t.Errorf("%s: got %d, want %d", url, w.Code, status)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "redirects.json")
+	os.WriteFile(name, []byte(`[{"exact": "/a", "target": "/b"}]`), 0644)
+	table, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(table, "GET", "/a"); w.Header().Get("Location") != "/b" {
+		t.Fatalf("got %q", w.Header().Get("Location"))
+	}
+	os.WriteFile(name, []byte(`[{"exact": "/a", "target": "/c"}]`), 0644)
+	if err := table.ReloadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(table, "GET", "/a"); w.Header().Get("Location") != "/c" {
+		t.Fatalf("got %q after reload", w.Header().Get("Location"))
+	}
+	if hits := table.Hits(); len(hits) != 1 || hits[0].Count != 1 {
+		t.Fatalf("hits not reset on reload: %v", hits)
+	}
+	// Invalid rules keep the current rules
+	if err := table.Reload([]Rule{{Exact: "/a"}}); !errors.Is(err, ErrNoTarget) {
+		t.Fatalf("got %v, want ErrNoTarget", err)
+	}
+	if err := table.Reload([]Rule{{Exact: "/a", Target: "https://example.com", Rewrite: true}}); !errors.Is(err, ErrRewriteURL) {
+		t.Fatalf("got %v, want ErrRewriteURL", err)
+	}
+	if err := table.Reload([]Rule{{Regex: "(", Target: "/"}}); err == nil {
+		t.Fatal("invalid regex accepted")
+	}
+	if w := serve(table, "GET", "/a"); w.Header().Get("Location") != "/c" {
+		t.Fatalf("got %q after failed reload", w.Header().Get("Location"))
+	}
+}
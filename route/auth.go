@@ -0,0 +1,165 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNoCredentials represents request without credentials of the scheme
+var ErrNoCredentials = errors.New("route: No credentials were found")
+
+// ErrUnauthenticated represents request without valid credentials
+var ErrUnauthenticated = errors.New("route: Request is not authenticated")
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	authErrorKey
+	challengeKey
+)
+
+// Principal store identity of an authenticated request.
+type Principal struct {
+	ID         string
+	Scheme     string
+	Roles      []string
+	Scopes     []string
+	Attributes map[string]interface{}
+}
+
+// Authenticator authenticates requests with a single scheme. It returns
+// ErrNoCredentials when the request carries no credentials of its scheme,
+// so the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthenticatorFunc acts as simple authenticator function to interface
+// converter.
+type AuthenticatorFunc func(*http.Request) (*Principal, error)
+
+// Authenticate implements route.Authenticator interface.
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return f(r)
+}
+
+// Challenger is implemented by authenticators that send WWW-Authenticate
+// challenge on unauthenticated responses.
+type Challenger interface {
+	Challenge() string
+}
+
+// Authentication is the middleware that authenticates requests with any of
+// the authenticators. The first authenticator that finds credentials on the
+// request decides the result.
+type Authentication struct {
+	Authenticators []Authenticator
+	// Optional passes unauthenticated requests without principal
+	Optional bool
+	// ErrorHandler handles rejected requests, defaults to 401 response
+	ErrorHandler http.Handler
+}
+
+// Authenticate creates middleware that requires valid credentials of any
+// of the authenticators.
+func Authenticate(authenticators ...Authenticator) *Authentication {
+	return &Authentication{Authenticators: authenticators}
+}
+
+// AuthenticateOptional creates middleware that authenticates requests with
+// credentials, but passes requests without credentials.
+func AuthenticateOptional(authenticators ...Authenticator) *Authentication {
+	return &Authentication{Authenticators: authenticators, Optional: true}
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Authentication) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	// Keep challenges for authorization of optionally authenticated
+	// requests
+	if m.Optional {
+		var challenges []string
+		for _, a := range m.Authenticators {
+			if c, ok := a.(Challenger); ok {
+				challenges = append(challenges, c.Challenge())
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), challengeKey, challenges))
+	}
+	for _, a := range m.Authenticators {
+		p, err := a.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		if err == nil && p == nil {
+			err = ErrUnauthenticated
+		}
+		if err != nil {
+			m.fail(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, WithPrincipal(r, p))
+		return
+	}
+	if m.Optional {
+		next.ServeHTTP(w, r)
+		return
+	}
+	m.fail(w, r, ErrNoCredentials)
+}
+
+// fail stores the failure reason and runs the error handler.
+func (m *Authentication) fail(w http.ResponseWriter, r *http.Request, err error) {
+	r = r.WithContext(context.WithValue(r.Context(), authErrorKey, err))
+	if m.ErrorHandler != nil {
+		m.ErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	for _, a := range m.Authenticators {
+		if c, ok := a.(Challenger); ok {
+			w.Header().Add("WWW-Authenticate", c.Challenge())
+		}
+	}
+	http.Error(w, "401 Unauthorized", 401)
+}
+
+// getChallenges gets challenges of authenticators of the request.
+func getChallenges(r *http.Request) []string {
+	c, _ := r.Context().Value(challengeKey).([]string)
+	return c
+}
+
+// WithPrincipal gets shallow copy of the request with the principal.
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey, p))
+}
+
+// GetPrincipal gets authenticated principal of the request.
+func GetPrincipal(r *http.Request) *Principal {
+	p, _ := r.Context().Value(principalKey).(*Principal)
+	return p
+}
+
+// GetAuthError gets the reason why authentication middleware rejected the
+// request.
+func GetAuthError(r *http.Request) error {
+	err, _ := r.Context().Value(authErrorKey).(error)
+	return err
+}
+
+// Authenticate adds authentication middleware with the authenticators to
+// current route.
+func (r *Route) Authenticate(authenticators ...Authenticator) *Route {
+	return r.Middleware(Authenticate(authenticators...))
+}
+
+// Authenticate registers a new route with authentication middleware.
+func (r *Router) Authenticate(authenticators ...Authenticator) *Route {
+	return r.NewRoute().Authenticate(authenticators...)
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Requirement define access requirement declared on a route. The principal
+// must have any of the roles, all of the scopes and permissions, and pass
+// all predicates.
+type Requirement struct {
+	Roles       []string    `json:"roles,omitempty"`
+	Scopes      []string    `json:"scopes,omitempty"`
+	Permissions []string    `json:"permissions,omitempty"`
+	Predicates  []Predicate `json:"predicates,omitempty"`
+}
+
+// Predicate define named attribute-based access check.
+type Predicate struct {
+	Name  string                               `json:"name"`
+	Check func(*http.Request, *Principal) bool `json:"-"`
+}
+
+// Policy decides whether the principal meets the route requirement.
+// Predicates are evaluated by the authorization middleware after the
+// policy allows the request.
+type Policy interface {
+	Authorize(r *http.Request, p *Principal, req Requirement) bool
+}
+
+// PolicyFunc acts as simple policy function to interface converter.
+type PolicyFunc func(*http.Request, *Principal, Requirement) bool
+
+// Authorize implements route.Policy interface.
+func (f PolicyFunc) Authorize(r *http.Request, p *Principal, req Requirement) bool {
+	return f(r, p, req)
+}
+
+// DefaultPolicy is used by routes of routers without policy.
+var DefaultPolicy Policy = DirectPolicy{}
+
+// DefaultChallenge is sent on 401 responses of requests without challenge of
+// an optional authenticator.
+var DefaultChallenge = "Bearer"
+
+// DirectPolicy checks roles and scopes directly against the principal.
+// Permission requirements are always denied, use a policy that maps roles
+// to permissions instead.
+type DirectPolicy struct{}
+
+// Authorize implements route.Policy interface.
+func (DirectPolicy) Authorize(r *http.Request, p *Principal, req Requirement) bool {
+	if len(req.Roles) > 0 && !HasAny(p.Roles, req.Roles) {
+		return false
+	}
+	return HasAll(p.Scopes, req.Scopes) && len(req.Permissions) == 0
+}
+
+// HasAny checks whether the set contains any of the values.
+func HasAny(set []string, values []string) bool {
+	for _, v := range values {
+		for _, s := range set {
+			if s == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAll checks whether the set contains all of the values.
+func HasAll(set []string, values []string) bool {
+	for _, v := range values {
+		if !HasAny(set, []string{v}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authorization is the middleware that enforces route requirement. It
+// responds 401 to requests without principal and 403 to principals that
+// do not meet the requirement.
+type Authorization struct {
+	Requirement
+	// Policy decides the requirement, defaults to the router policy
+	Policy Policy
+	// ErrorHandler handles rejected requests, defaults to 401 and 403
+	// responses
+	ErrorHandler http.Handler
+	router       *policyRef
+}
+
+// policyRef store router policy, inherited from the parent router unless
+// the router sets its own
+type policyRef struct {
+	mu     sync.RWMutex
+	policy Policy
+	parent *policyRef
+}
+
+// set sets policy of the router.
+func (p *policyRef) set(policy Policy) {
+	p.mu.Lock()
+	p.policy = policy
+	p.mu.Unlock()
+}
+
+// get gets policy of the router or its nearest parent.
+func (p *policyRef) get() Policy {
+	for ; p != nil; p = p.parent {
+		p.mu.RLock()
+		policy := p.policy
+		p.mu.RUnlock()
+		if policy != nil {
+			return policy
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Authorization) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	p := GetPrincipal(r)
+	if p == nil {
+		m.fail(w, r, 401)
+		return
+	}
+	// Router policy is resolved on request, so that it applies to routes
+	// registered before the policy was set
+	policy := m.Policy
+	if policy == nil {
+		policy = m.router.get()
+	}
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	if !policy.Authorize(r, p, m.Requirement) {
+		m.fail(w, r, 403)
+		return
+	}
+	for _, pred := range m.Predicates {
+		if !pred.Check(r, p) {
+			m.fail(w, r, 403)
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// fail runs the error handler or writes the status response.
+func (m *Authorization) fail(w http.ResponseWriter, r *http.Request, status int) {
+	if m.ErrorHandler != nil {
+		m.ErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	if status == 401 {
+		// Challenge with schemes of the authenticators on the request
+		for _, c := range getChallenges(r) {
+			w.Header().Add("WWW-Authenticate", c)
+		}
+		if len(w.Header()["Www-Authenticate"]) == 0 {
+			w.Header().Set("WWW-Authenticate", DefaultChallenge)
+		}
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Policy sets authorization policy for requirements declared on routes of
+// the router and its subrouters, including routes registered before. The
+// policy of subrouters that set their own policy is kept.
+func (r *Router) Policy(policy Policy) *Router {
+	r.policy.set(policy)
+	return r
+}
+
+// Require adds authorization middleware with the requirement to current
+// route.
+func (r *Route) Require(req Requirement) *Route {
+	return r.Middleware(&Authorization{
+		Requirement: req,
+		router:      r.policy,
+	})
+}
+
+// RequireRoles requires the principal to have any of the roles.
+func (r *Route) RequireRoles(roles ...string) *Route {
+	return r.Require(Requirement{Roles: roles})
+}
+
+// RequireScopes requires the principal to have all of the scopes.
+func (r *Route) RequireScopes(scopes ...string) *Route {
+	return r.Require(Requirement{Scopes: scopes})
+}
+
+// RequirePermissions requires the principal to have all of the
+// permissions.
+func (r *Route) RequirePermissions(permissions ...string) *Route {
+	return r.Require(Requirement{Permissions: permissions})
+}
+
+// RequireFunc requires the principal to pass the named predicate.
+func (r *Route) RequireFunc(name string, f func(*http.Request, *Principal) bool) *Route {
+	return r.Require(Requirement{Predicates: []Predicate{{Name: name, Check: f}}})
+}
+
+// Require registers a new route with authorization middleware.
+func (r *Router) Require(req Requirement) *Route {
+	return r.NewRoute().Require(req)
+}
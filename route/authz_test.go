@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// headerAuthenticator authenticates requests with X-User header
+type headerAuthenticator struct{}
+
+func (headerAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	user := r.Header.Get("X-User")
+	if user == "" {
+		return nil, ErrNoCredentials
+	}
+	return &Principal{ID: user, Roles: []string{user}}, nil
+}
+
+func (headerAuthenticator) Challenge() string {
+	return `Header realm="test"`
+}
+
+func serveUser(h http.Handler, path, user string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", path, nil)
+	if user != "" {
+		r.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAuthorizationChallenge(t *testing.T) {
+	router := NewRouter()
+	router.Authenticate(headerAuthenticator{}).RequireRoles("admin").GetFunc("/strict", func(http.ResponseWriter, *http.Request) {})
+	router.Middleware(AuthenticateOptional(headerAuthenticator{})).RequireRoles("admin").GetFunc("/optional", func(http.ResponseWriter, *http.Request) {})
+
+	for _, path := range []string{"/strict", "/optional"} {
+		w := serveUser(router, path, "")
+		if w.Code != 401 || w.Header().Get("WWW-Authenticate") != `Header realm="test"` {
+			t.Errorf("%s: got %d with challenge %q", path, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+		if w := serveUser(router, path, "guest"); w.Code != 403 {
+			t.Errorf("%s: guest got %d, want 403", path, w.Code)
+		}
+		if w := serveUser(router, path, "admin"); w.Code != 200 {
+			t.Errorf("%s: admin got %d, want 200", path, w.Code)
+		}
+	}
+
+	// Requirements without authentication fall back to default challenge
+	bare := NewRouter()
+	bare.NewRoute().RequireRoles("admin").GetFunc("/", func(http.ResponseWriter, *http.Request) {})
+	if w := serveUser(bare, "/", ""); w.Code != 401 || w.Header().Get("WWW-Authenticate") != DefaultChallenge {
+		t.Errorf("got %d with challenge %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestRouterPolicy(t *testing.T) {
+	allow := PolicyFunc(func(*http.Request, *Principal, Requirement) bool { return true })
+	deny := PolicyFunc(func(*http.Request, *Principal, Requirement) bool { return false })
+
+	router := NewRouter()
+	router.Authenticate(headerAuthenticator{}).RequireRoles("admin").GetFunc("/root", func(http.ResponseWriter, *http.Request) {})
+	var own *Router
+	router.Authenticate(headerAuthenticator{}).PathPrefix("/inherited").Group(func(r *Router) {
+		r.NewRoute().RequireRoles("admin").GetFunc("/x", func(http.ResponseWriter, *http.Request) {})
+	})
+	router.Authenticate(headerAuthenticator{}).PathPrefix("/own").Group(func(r *Router) {
+		own = r
+		r.NewRoute().RequireRoles("admin").GetFunc("/x", func(http.ResponseWriter, *http.Request) {})
+	})
+
+	// Policies set after routes are registered apply to them
+	if w := serveUser(router, "/root", "guest"); w.Code != 403 {
+		t.Fatalf("default policy: got %d, want 403", w.Code)
+	}
+	router.Policy(allow)
+	own.Policy(deny)
+	tests := map[string]int{"/root": 200, "/inherited/x": 200, "/own/x": 403}
+	for path, status := range tests {
+		if w := serveUser(router, path, "guest"); w.Code != status {
+			t.Errorf("%s: got %d, want %d", path, w.Code, status)
+		}
+	}
+}
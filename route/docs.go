@@ -10,6 +10,21 @@ the same API function syntax but does not directly compatible with library
 mix-and-matching because it has some wrapper to pass the middleware
 information.
 
+Routes and groups may declare authentication with any of several
+Authenticator schemes, and access requirements evaluated by a pluggable
+Policy. Router.Routes lists the registered routes with their requirements for
+security audits.
+
+	router.Policy(rbac)
+	router.PathPrefix("/admin").Authenticate(basic, jwt).
+		RequirePermissions("reports.view").Group(func(r *route.Router) {
+			r.GetFunc("/reports", reports)
+		})
+
+Route.Timeout sets per-route request deadline, responding 503 to handlers
+that overrun it before writing. DeadlineTransport forwards the remaining time
+to upstream services in the X-Request-Timeout header.
+
 For more information about the Gorilla Mux package documentation, please
 head over to http://godoc.org/github.com/gorilla/mux.
 */
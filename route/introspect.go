@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// registry store middleware stack and priority of routes for
+// introspection
+type registry struct {
+	mu     sync.RWMutex
+	routes map[*mux.Route]routeEntry
+}
+
+// routeEntry store registered state of a route
+type routeEntry struct {
+	middleware []Middleware
+	priority   int
+}
+
+// newRegistry creates empty route registry.
+func newRegistry() *registry {
+	return &registry{routes: make(map[*mux.Route]routeEntry)}
+}
+
+// set records middleware stack and priority of the route.
+func (g *registry) set(route *mux.Route, middleware []Middleware, priority int) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	g.routes[route] = routeEntry{middleware: middleware, priority: priority}
+	g.mu.Unlock()
+}
+
+// get gets registered state of the route.
+func (g *registry) get(route *mux.Route) routeEntry {
+	if g == nil {
+		return routeEntry{}
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.routes[route]
+}
+
+// RouteInfo store route description for introspection and security
+// audits.
+type RouteInfo struct {
+	Name          string        `json:"name,omitempty"`
+	Methods       []string      `json:"methods,omitempty"`
+	Host          string        `json:"host,omitempty"`
+	Path          string        `json:"path,omitempty"`
+	Authenticated bool          `json:"authenticated"`
+	Requirements  []Requirement `json:"requirements,omitempty"`
+	Priority      int           `json:"priority,omitempty"`
+}
+
+// Routes lists routes with handlers registered on the router, including
+// their authentication and authorization requirements.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	r.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		info := RouteInfo{Name: route.GetName()}
+		info.Methods, _ = route.GetMethods()
+		info.Host, _ = route.GetHostTemplate()
+		info.Path, _ = route.GetPathTemplate()
+		entry := r.registry.get(route)
+		info.Priority = entry.priority
+		for _, m := range entry.middleware {
+			switch m := m.(type) {
+			case *Authentication:
+				info.Authenticated = info.Authenticated || !m.Optional
+			case Authenticator:
+				// Middleware such as JWT that authenticate by themselves
+				info.Authenticated = true
+			case *Authorization:
+				info.Authenticated = true
+				info.Requirements = append(info.Requirements, m.Requirement)
+			}
+		}
+		routes = append(routes, info)
+		return nil
+	})
+	return routes
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Priority sets load shedding priority class of current route and routes of
+// its subrouter. Requests of higher priority are admitted first when the
+// server is overloaded, negative priority requests are shed first.
+func (r *Route) Priority(class int) *Route {
+	r.priority = class
+	// Update registry if handler already defined
+	if r.handler != nil {
+		r.registry.set(r.Route, r.middleware, r.priority)
+	}
+	return r
+}
+
+// PriorityOf gets priority class of the route matching the request. It
+// implements serve.Classifier interface, so the router classifies requests
+// of the server before they are admitted.
+func (r *Router) PriorityOf(req *http.Request) int {
+	var match mux.RouteMatch
+	if !r.Router.Match(req, &match) || match.Route == nil {
+		return 0
+	}
+	return r.registry.get(match.Route).priority
+}
@@ -17,6 +17,9 @@ type Route struct {
 	*mux.Route
 	middleware []Middleware
 	handler    http.Handler
+	policy     *policyRef
+	priority   int
+	registry   *registry
 }
 
 // BuildVarsFunc adds a custom function to be used to modify build variables
@@ -28,7 +31,9 @@ func (r *Route) BuildVarsFunc(f mux.BuildVarsFunc) *Route {
 
 // Middleware adds middleware function to current route.
 func (r *Route) Middleware(middleware ...Middleware) *Route {
-	r.middleware = append(r.middleware, middleware...)
+	// Limit capacity to copy the stack shared with sibling routes
+	n := len(r.middleware)
+	r.middleware = append(r.middleware[:n:n], middleware...)
 	// Reapply r.Handler if already defined
 	if r.handler != nil {
 		r.Handler(r.handler)
@@ -52,6 +57,7 @@ func (r *Route) Handler(handler http.Handler) *Route {
 		r.Route.Handler(handler)
 	}
 	r.handler = handler
+	r.registry.set(r.Route, r.middleware, r.priority)
 
 	return r
 }
@@ -66,6 +72,9 @@ func (r *Route) wrapRouter(router *mux.Router) *Router {
 	return &Router{
 		Router:     router,
 		middleware: r.middleware,
+		policy:     &policyRef{parent: r.policy},
+		priority:   r.priority,
+		registry:   r.registry,
 	}
 }
 
@@ -21,12 +21,17 @@ var ErrRouteNotFound = errors.New("route: Named route was not found")
 type Router struct {
 	*mux.Router
 	middleware []Middleware
+	policy     *policyRef
+	priority   int
+	registry   *registry
 }
 
 // NewRouter returns a new router instance.
 func NewRouter() *Router {
 	router := &Router{
-		Router: mux.NewRouter(),
+		Router:   mux.NewRouter(),
+		policy:   &policyRef{},
+		registry: newRegistry(),
 	}
 	router.NotFoundHandler = NotFoundHandler
 	return router
@@ -45,6 +50,9 @@ func (r *Router) wrapRoute(route *mux.Route) *Route {
 	return &Route{
 		Route:      route,
 		middleware: r.middleware,
+		policy:     r.policy,
+		priority:   r.priority,
+		registry:   r.registry,
 	}
 }
 
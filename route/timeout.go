@@ -0,0 +1,226 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeadlineHeader carries remaining request time in milliseconds to
+// upstream services.
+const DeadlineHeader = "X-Request-Timeout"
+
+// Timeout is the middleware that sets request context deadline. Requests
+// overrunning the deadline before writing their response receive timeout
+// response, while the handler is expected to return once the context is
+// done. Handlers that already started writing are cut by the context only.
+type Timeout struct {
+	Duration time.Duration
+	// Status of timeout response, defaults to 503
+	Status int
+	// Body of timeout response, defaults to status text
+	Body string
+	// TrustHeader shortens the deadline to the remaining time sent by the
+	// caller in DeadlineHeader
+	TrustHeader bool
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (m *Timeout) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	d := m.Duration
+	if m.TrustHeader {
+		if ms, err := strconv.ParseInt(r.Header.Get(DeadlineHeader), 10, 64); err == nil && ms > 0 {
+			if h := time.Duration(ms) * time.Millisecond; d <= 0 || h < d {
+				d = h
+			}
+		}
+	}
+	if d <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	// Extend connection write deadline for routes longer than server
+	// timeout, leaving time to write timeout response
+	deadline, _ := ctx.Deadline()
+	http.NewResponseController(w).SetWriteDeadline(deadline.Add(time.Second))
+
+	tw := &timeoutWriter{ResponseWriter: w, header: w.Header().Clone()}
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(done)
+		if ctx.Err() == context.DeadlineExceeded {
+			tw.timeout(m.status(), m.Body)
+		}
+	})
+	// Wait for timeout response already being written, the response writer
+	// is not valid once this function returns
+	defer func() {
+		if !stop() {
+			<-done
+		}
+	}()
+	next.ServeHTTP(tw, r.WithContext(ctx))
+}
+
+// status gets timeout response status.
+func (m *Timeout) status() int {
+	if m.Status > 0 {
+		return m.Status
+	}
+	return 503
+}
+
+// timeoutWriter serializes handler writes with the timeout response. The
+// handler gets its own header map, so it can not race the timeout response.
+type timeoutWriter struct {
+	http.ResponseWriter
+	header   http.Header
+	mu       sync.Mutex
+	wrote    bool
+	timedOut bool
+}
+
+// timeout writes timeout response unless the handler already wrote.
+func (w *timeoutWriter) timeout(status int, body string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wrote {
+		return
+	}
+	w.wrote, w.timedOut = true, true
+	if body == "" {
+		body = strconv.Itoa(status) + " " + http.StatusText(status)
+	}
+	h := w.ResponseWriter.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(status)
+	w.ResponseWriter.Write([]byte(body))
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Header implements http.ResponseWriter interface.
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.start()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// start copies handler header before the response is written.
+func (w *timeoutWriter) start() {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	h := w.ResponseWriter.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	for k, v := range w.header {
+		h[k] = v
+	}
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface.
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.start()
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	w.start()
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap gets the underlying response writer.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Timeout adds timeout middleware with the duration to current route.
+func (r *Route) Timeout(d time.Duration) *Route {
+	return r.Middleware(&Timeout{Duration: d})
+}
+
+// Timeout registers a new route with timeout middleware.
+func (r *Router) Timeout(d time.Duration) *Route {
+	return r.NewRoute().Timeout(d)
+}
+
+// SetDeadlineHeader sets remaining time of the request context deadline on
+// the outbound request header.
+func SetDeadlineHeader(req *http.Request) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	req.Header.Set(DeadlineHeader, strconv.FormatInt(ms, 10))
+}
+
+// DeadlineTransport propagates context deadline of outbound requests with
+// DeadlineHeader.
+type DeadlineTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *DeadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Deadline(); ok {
+		req = req.Clone(req.Context())
+		SetDeadlineHeader(req)
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutResponse(t *testing.T) {
+	written := make(chan error, 1)
+	h := MiddlewareRunner{
+		Stack: []Middleware{&Timeout{Duration: 10 * time.Millisecond, Body: "too slow"}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Give the timeout response time to be written
+			<-r.Context().Done()
+			time.Sleep(50 * time.Millisecond)
+			w.Header().Set("X-Handler", "1")
+			_, err := w.Write([]byte("late"))
+			written <- err
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 503 || w.Body.String() != "too slow" || w.Header().Get("X-Handler") != "" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if err := <-written; err != http.ErrHandlerTimeout {
+		t.Fatalf("late write error %v", err)
+	}
+}
+
+func TestTimeoutWritten(t *testing.T) {
+	h := MiddlewareRunner{
+		Stack: []Middleware{&Timeout{Duration: 10 * time.Millisecond}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Handler", "1")
+			w.Write([]byte("partial"))
+			<-r.Context().Done()
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != "partial" || w.Header().Get("X-Handler") != "1" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestTimeoutTrustHeader(t *testing.T) {
+	var remaining time.Duration
+	h := MiddlewareRunner{
+		Stack: []Middleware{&Timeout{Duration: time.Minute, TrustHeader: true}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			remaining = time.Until(deadline)
+		}),
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(DeadlineHeader, "500")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if remaining <= 0 || remaining > 500*time.Millisecond {
+		t.Fatalf("remaining %v", remaining)
+	}
+}
+
+// TestTimeoutRace returns handlers right at the deadline, so the timeout
+// response races the handler return. Run it with -race.
+func TestTimeoutRace(t *testing.T) {
+	h := MiddlewareRunner{
+		Stack: []Middleware{&Timeout{Duration: time.Millisecond}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}),
+	}
+	for i := 0; i < 500; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		// The recorder is read right after the handler returns
+		if w.Code != 503 && w.Code != 200 {
+			t.Fatalf("got %d %q", w.Code, w.Body.String())
+		}
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	for i := 0; i < 100; i++ {
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+}
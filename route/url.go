@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+// ErrOddPairs define odd number of key value pairs on URL building
+var ErrOddPairs = errors.New("route: Number of URL parameters should be even")
+
+// BuildURL builds URL from a named route. Parameters that are not route
+// variables are appended as query parameters.
+func (r *Router) BuildURL(name string, pairs ...string) (*url.URL, error) {
+	route := r.Router.Get(name)
+	if route == nil {
+		return nil, ErrRouteNotFound
+	}
+	if len(pairs)%2 != 0 {
+		return nil, ErrOddPairs
+	}
+	// Separate route variables from query parameters
+	names, err := route.GetVarNames()
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]bool, len(names))
+	for _, n := range names {
+		vars[n] = true
+	}
+	var varPairs []string
+	query := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		if vars[pairs[i]] {
+			varPairs = append(varPairs, pairs[i], pairs[i+1])
+		} else {
+			query.Add(pairs[i], pairs[i+1])
+		}
+	}
+	u, err := route.URL(varPairs...)
+	if err != nil {
+		return nil, err
+	}
+	// Merge query parameters with route query matchers
+	if len(query) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += query.Encode()
+	}
+	return u, nil
+}
+
+// BuildAbsoluteURL builds absolute URL from a named route. Routes without
+// host matcher take the scheme and host from the request.
+func (r *Router) BuildAbsoluteURL(req *http.Request, name string, pairs ...string) (*url.URL, error) {
+	u, err := r.BuildURL(name, pairs...)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		u.Scheme = RequestScheme(req)
+		u.Host = req.Host
+	}
+	return u, nil
+}
+
+// RequestScheme gets the URL scheme of the request.
+func RequestScheme(r *http.Request) string {
+	if r.URL != nil && r.URL.Scheme != "" {
+		return r.URL.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// ValidateName checks whether the named route was registered.
+func (r *Router) ValidateName(name string) error {
+	if r.Router.Get(name) == nil {
+		return ErrRouteNotFound
+	}
+	return nil
+}
+
+// FuncMap gets template functions for URL building. The url function
+// builds relative URL from route name and key value pairs.
+//
+//	<a href="{{url "user" "id" .ID "tab" "posts"}}">Posts</a>
+func (r *Router) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url": func(name string, pairs ...interface{}) (string, error) {
+			u, err := r.BuildURL(name, stringPairs(pairs)...)
+			if err != nil {
+				return "", err
+			}
+			return u.String(), nil
+		},
+	}
+}
+
+// RequestFuncMap gets template functions bound to the request. The absurl
+// function builds absolute URL with the request scheme and host.
+//
+//	<link rel="canonical" href="{{absurl "user" "id" .ID}}">
+func (r *Router) RequestFuncMap() map[string]func(*http.Request) interface{} {
+	return map[string]func(*http.Request) interface{}{
+		"absurl": func(req *http.Request) interface{} {
+			return func(name string, pairs ...interface{}) (string, error) {
+				u, err := r.BuildAbsoluteURL(req, name, stringPairs(pairs)...)
+				if err != nil {
+					return "", err
+				}
+				return u.String(), nil
+			}
+		},
+	}
+}
+
+// Validators gets name validators of the URL building template functions.
+func (r *Router) Validators() map[string]func(string) error {
+	return map[string]func(string) error{
+		"url":    r.ValidateName,
+		"absurl": r.ValidateName,
+	}
+}
+
+// stringPairs formats template function arguments as URL parameters.
+func stringPairs(pairs []interface{}) []string {
+	s := make([]string, len(pairs))
+	for i, p := range pairs {
+		s[i] = fmt.Sprint(p)
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package route
+
+import (
+	"bytes"
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func urlRouter() *Router {
+	r := NewRouter()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	r.GetFunc("/users/{id}", noop).Name("user")
+	r.GetFunc("/search", noop).Queries("type", "{type}").Name("search")
+	r.Host("api.example.com").Path("/v1/items/{id}").HandlerFunc(noop).Name("item")
+	return r
+}
+
+func TestBuildURL(t *testing.T) {
+	r := urlRouter()
+	tests := []struct {
+		name  string
+		pairs []string
+		want  string
+		err   error
+	}{
+		{"user", []string{"id", "7"}, "/users/7", nil},
+		{"user", []string{"id", "7", "tab", "posts", "q", "a b"}, "/users/7?q=a+b&tab=posts", nil},
+		{"search", []string{"type", "book", "q", "go"}, "/search?type=book&q=go", nil},
+		{"item", []string{"id", "3"}, "http://api.example.com/v1/items/3", nil},
+		{"user", []string{"id"}, "", ErrOddPairs},
+		{"missing", nil, "", ErrRouteNotFound},
+	}
+	for _, tt := range tests {
+		u, err := r.BuildURL(tt.name, tt.pairs...)
+		if err != tt.err {
+			t.Errorf("%s %v: got error %v, want %v", tt.name, tt.pairs, err, tt.err)
+			continue
+		}
+		if err == nil && u.String() != tt.want {
+			t.Errorf("%s %v: got %s, want %s", tt.name, tt.pairs, u, tt.want)
+		}
+	}
+}
+
+func TestBuildAbsoluteURL(t *testing.T) {
+	r := urlRouter()
+	plain := httptest.NewRequest("GET", "http://www.example.com/", nil)
+	secure := httptest.NewRequest("GET", "/", nil)
+	secure.Host, secure.TLS = "shop.example.com", &tls.ConnectionState{}
+	tests := []struct {
+		req   *http.Request
+		name  string
+		pairs []string
+		want  string
+	}{
+		{plain, "user", []string{"id", "7", "tab", "posts"}, "http://www.example.com/users/7?tab=posts"},
+		{secure, "user", []string{"id", "7"}, "https://shop.example.com/users/7"},
+		{secure, "item", []string{"id", "3"}, "http://api.example.com/v1/items/3"},
+	}
+	for _, tt := range tests {
+		u, err := r.BuildAbsoluteURL(tt.req, tt.name, tt.pairs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s %v: got %s, want %s", tt.name, tt.pairs, u, tt.want)
+		}
+	}
+	if _, err := r.BuildAbsoluteURL(plain, "missing"); err != ErrRouteNotFound {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestURLFuncs(t *testing.T) {
+	r := urlRouter()
+	req := httptest.NewRequest("GET", "http://www.example.com/", nil)
+	tmpl := template.Must(template.New("").Funcs(r.FuncMap()).Funcs(template.FuncMap{
+		"absurl": r.RequestFuncMap()["absurl"](req),
+	}).Parse(`{{url "user" "id" .}} {{absurl "user" "id" . "n" 2}}`))
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "/users/5 http://www.example.com/users/5?n=2" {
+		t.Fatalf("got %q", got)
+	}
+	validators := r.Validators()
+	for _, name := range []string{"url", "absurl"} {
+		if err := validators[name]("user"); err != nil {
+			t.Errorf("%s user: %v", name, err)
+		}
+		if err := validators[name]("missing"); err != ErrRouteNotFound {
+			t.Errorf("%s missing: got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// Common Content-Security-Policy source expressions
+const (
+	Self          = "'self'"
+	None          = "'none'"
+	UnsafeInline  = "'unsafe-inline'"
+	UnsafeEval    = "'unsafe-eval'"
+	StrictDynamic = "'strict-dynamic'"
+	// Nonce is replaced with the request nonce, such as 'nonce-abc'
+	Nonce = "'nonce'"
+)
+
+// reportGroup define Reporting API endpoint name of violation reports
+const reportGroup = "csp-endpoint"
+
+// Policy builds Content-Security-Policy header value.
+type Policy struct {
+	// ReportOnly reports violations without enforcing the policy
+	ReportOnly bool
+	directives []directive
+	reportURI  string
+	nonce      bool
+}
+
+// directive store a policy directive with its sources
+type directive struct {
+	name    string
+	sources []string
+}
+
+// NewPolicy creates empty content security policy.
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Add appends sources to the policy directive.
+func (p *Policy) Add(name string, sources ...string) *Policy {
+	for _, src := range sources {
+		if src == Nonce {
+			p.nonce = true
+		}
+	}
+	for i := range p.directives {
+		if p.directives[i].name == name {
+			p.directives[i].sources = append(p.directives[i].sources, sources...)
+			return p
+		}
+	}
+	p.directives = append(p.directives, directive{name: name, sources: sources})
+	return p
+}
+
+// ReportTo sends violation reports to the URI, using both report-uri and
+// the Reporting API report-to directive.
+func (p *Policy) ReportTo(uri string) *Policy {
+	p.reportURI = uri
+	return p
+}
+
+// UsesNonce checks whether the policy has Nonce source.
+func (p *Policy) UsesNonce() bool {
+	return p.nonce
+}
+
+// Build gets policy header value with the nonce.
+func (p *Policy) Build(nonce string) string {
+	var parts []string
+	for _, d := range p.directives {
+		part := d.name
+		for _, src := range d.sources {
+			if src == Nonce {
+				src = "'nonce-" + nonce + "'"
+			}
+			part += " " + src
+		}
+		parts = append(parts, part)
+	}
+	if p.reportURI != "" {
+		parts = append(parts, "report-uri "+p.reportURI, "report-to "+reportGroup)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// setHeader sets policy response headers with the nonce.
+func (p *Policy) setHeader(h http.Header, nonce string) {
+	name := "Content-Security-Policy"
+	if p.ReportOnly {
+		name += "-Report-Only"
+	}
+	h.Set(name, p.Build(nonce))
+	if p.reportURI != "" {
+		h.Set("Reporting-Endpoints", reportGroup+`="`+p.reportURI+`"`)
+	}
+}
+
+// newNonce generates random base64 encoded nonce.
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package secure sets security related response headers such as HSTS,
+X-Content-Type-Options, Referrer-Policy, Permissions-Policy, cross-origin
+isolation policies, frame options, and Content-Security-Policy.
+
+	policy := secure.NewPolicy().
+		Add("default-src", secure.Self).
+		Add("script-src", secure.Self, secure.Nonce).
+		ReportTo("/csp-report")
+	router.Middleware(secure.New(secure.Options{
+		HSTSMaxAge:    365 * 24 * time.Hour,
+		NoSniff:       true,
+		FrameOptions:  "DENY",
+		ContentPolicy: policy,
+	}))
+
+Policies that use the Nonce source get a fresh nonce on every request. Get it
+with secure.GetNonce, or register RequestFuncMap as view request functions to
+use it from templates.
+
+	<script nonce="{{cspNonce}}">...</script>
+
+Violation reports sent by browsers are collected by ReportHandler, which can
+be mounted on a router.
+
+	router.Post("/csp-report", secure.NewReportHandler(func(r secure.Report) {
+		log.Printf("CSP violation: %s on %s", r.EffectiveDirective, r.DocumentURI)
+	}))
+*/
+package secure
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,153 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package secure
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxReportBytes define maximum accepted violation report body size
+const maxReportBytes = 64 << 10
+
+// Report store a Content-Security-Policy violation report
+type Report struct {
+	DocumentURI        string
+	Referrer           string
+	BlockedURI         string
+	EffectiveDirective string
+	OriginalPolicy     string
+	Disposition        string
+	SourceFile         string
+	LineNumber         int
+	ColumnNumber       int
+	StatusCode         int
+	UserAgent          string
+}
+
+// legacyReport define report-uri request body
+type legacyReport struct {
+	Body struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		StatusCode         int    `json:"status-code"`
+	} `json:"csp-report"`
+}
+
+// reportingReport define Reporting API request body item
+type reportingReport struct {
+	Type      string `json:"type"`
+	UserAgent string `json:"user_agent"`
+	Body      struct {
+		DocumentURL        string `json:"documentURL"`
+		Referrer           string `json:"referrer"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		OriginalPolicy     string `json:"originalPolicy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		ColumnNumber       int    `json:"columnNumber"`
+		StatusCode         int    `json:"statusCode"`
+	} `json:"body"`
+}
+
+// ReportHandler collects violation reports sent by browsers, in both the
+// report-uri and the Reporting API formats.
+type ReportHandler struct {
+	Collect func(Report)
+}
+
+// NewReportHandler creates violation report handler with collector function.
+func NewReportHandler(collect func(Report)) *ReportHandler {
+	return &ReportHandler{Collect: collect}
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *ReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "405 Method Not Allowed", 405)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReportBytes))
+	if err != nil {
+		http.Error(w, "400 Bad Request", 400)
+		return
+	}
+	reports, err := parseReports(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, "400 Bad Request", 400)
+		return
+	}
+	for _, report := range reports {
+		if report.UserAgent == "" {
+			report.UserAgent = r.UserAgent()
+		}
+		h.Collect(report)
+	}
+	w.WriteHeader(204)
+}
+
+// parseReports decodes violation reports from the request body.
+func parseReports(contentType string, body []byte) ([]Report, error) {
+	if strings.HasPrefix(contentType, "application/reports+json") {
+		var items []reportingReport
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		var reports []Report
+		for _, item := range items {
+			if item.Type != "csp-violation" {
+				continue
+			}
+			b := item.Body
+			reports = append(reports, Report{
+				DocumentURI:        b.DocumentURL,
+				Referrer:           b.Referrer,
+				BlockedURI:         b.BlockedURL,
+				EffectiveDirective: b.EffectiveDirective,
+				OriginalPolicy:     b.OriginalPolicy,
+				Disposition:        b.Disposition,
+				SourceFile:         b.SourceFile,
+				LineNumber:         b.LineNumber,
+				ColumnNumber:       b.ColumnNumber,
+				StatusCode:         b.StatusCode,
+				UserAgent:          item.UserAgent,
+			})
+		}
+		return reports, nil
+	}
+	var legacy legacyReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	b := legacy.Body
+	if b.EffectiveDirective == "" {
+		b.EffectiveDirective = b.ViolatedDirective
+	}
+	return []Report{{
+		DocumentURI:        b.DocumentURI,
+		Referrer:           b.Referrer,
+		BlockedURI:         b.BlockedURI,
+		EffectiveDirective: b.EffectiveDirective,
+		OriginalPolicy:     b.OriginalPolicy,
+		Disposition:        b.Disposition,
+		SourceFile:         b.SourceFile,
+		LineNumber:         b.LineNumber,
+		ColumnNumber:       b.ColumnNumber,
+		StatusCode:         b.StatusCode,
+	}}, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package secure
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	nonceKey contextKey = iota
+)
+
+// Options store security header configurations. Empty values omit the
+// corresponding header.
+type Options struct {
+	// HSTSMaxAge enables Strict-Transport-Security on HTTPS responses
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// NoSniff sets X-Content-Type-Options to nosniff
+	NoSniff bool
+	// FrameOptions is the X-Frame-Options value, such as DENY
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy value
+	ReferrerPolicy string
+	// PermissionsPolicy is the Permissions-Policy value
+	PermissionsPolicy string
+	// CrossOriginOpenerPolicy is the Cross-Origin-Opener-Policy value
+	CrossOriginOpenerPolicy string
+	// CrossOriginEmbedderPolicy is the Cross-Origin-Embedder-Policy value
+	CrossOriginEmbedderPolicy string
+	// CrossOriginResourcePolicy is the Cross-Origin-Resource-Policy value
+	CrossOriginResourcePolicy string
+	// ContentPolicy is the Content-Security-Policy of every response
+	ContentPolicy *Policy
+}
+
+// DefaultOptions define recommended security headers for HTML applications
+var DefaultOptions = Options{
+	HSTSMaxAge:              365 * 24 * time.Hour,
+	HSTSIncludeSubdomains:   true,
+	NoSniff:                 true,
+	FrameOptions:            "DENY",
+	ReferrerPolicy:          "strict-origin-when-cross-origin",
+	CrossOriginOpenerPolicy: "same-origin",
+}
+
+// Secure is the middleware that sets security headers on every response.
+type Secure struct {
+	Options
+	hsts string
+}
+
+// New creates security headers middleware.
+func New(o Options) *Secure {
+	s := &Secure{Options: o}
+	// Build HSTS header value once
+	if o.HSTSMaxAge > 0 {
+		s.hsts = "max-age=" + strconv.FormatInt(int64(o.HSTSMaxAge/time.Second), 10)
+		if o.HSTSIncludeSubdomains {
+			s.hsts += "; includeSubDomains"
+		}
+		if o.HSTSPreload {
+			s.hsts += "; preload"
+		}
+	}
+	return s
+}
+
+// ServeHTTP implements route.Middleware interface.
+func (s *Secure) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	h := w.Header()
+	if s.hsts != "" && route.RequestScheme(r) == "https" {
+		h.Set("Strict-Transport-Security", s.hsts)
+	}
+	if s.NoSniff {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+	setHeader(h, "X-Frame-Options", s.FrameOptions)
+	setHeader(h, "Referrer-Policy", s.ReferrerPolicy)
+	setHeader(h, "Permissions-Policy", s.PermissionsPolicy)
+	setHeader(h, "Cross-Origin-Opener-Policy", s.CrossOriginOpenerPolicy)
+	setHeader(h, "Cross-Origin-Embedder-Policy", s.CrossOriginEmbedderPolicy)
+	setHeader(h, "Cross-Origin-Resource-Policy", s.CrossOriginResourcePolicy)
+	// Set content security policy with a fresh nonce
+	if p := s.ContentPolicy; p != nil {
+		nonce := ""
+		if p.UsesNonce() {
+			nonce = newNonce()
+			r = r.WithContext(context.WithValue(r.Context(), nonceKey, nonce))
+		}
+		p.setHeader(h, nonce)
+	}
+	next.ServeHTTP(w, r)
+}
+
+// setHeader sets response header unless the value is empty.
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
+
+// GetNonce gets Content-Security-Policy nonce of the request.
+func GetNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(nonceKey).(string)
+	return nonce
+}
+
+// RequestFuncMap gets template functions bound to the request, to be
+// registered as view request functions. The cspNonce function gets the
+// Content-Security-Policy nonce of the request.
+func RequestFuncMap() map[string]func(*http.Request) interface{} {
+	return map[string]func(*http.Request) interface{}{
+		"cspNonce": func(r *http.Request) interface{} {
+			return func() string {
+				return GetNonce(r)
+			}
+		},
+	}
+}
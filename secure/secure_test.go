@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package secure
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mandala/omnibus/route"
+)
+
+// serve runs the middleware and gets the response with the nonce seen by
+// the handler.
+func serve(s *Secure, r *http.Request) (*httptest.ResponseRecorder, string) {
+	var nonce string
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{s},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = GetNonce(r)
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w, nonce
+}
+
+func TestSecureHeaders(t *testing.T) {
+	o := DefaultOptions
+	o.HSTSPreload = true
+	o.PermissionsPolicy = "camera=()"
+	s := New(o)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w, _ := serve(s, r)
+	want := map[string]string{
+		"Strict-Transport-Security":    "max-age=31536000; includeSubDomains; preload",
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "strict-origin-when-cross-origin",
+		"Permissions-Policy":           "camera=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "",
+		"Content-Security-Policy":      "",
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s: got %q, want %q", k, got, v)
+		}
+	}
+	// HSTS is only sent over HTTPS
+	w, _ = serve(s, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Fatalf("plain HTTP got HSTS %q", got)
+	}
+}
+
+func TestContentPolicyNonce(t *testing.T) {
+	p := NewPolicy().Add("default-src", Self).Add("script-src", Self, Nonce).Add("script-src", StrictDynamic)
+	s := New(Options{ContentPolicy: p})
+	w, nonce := serve(s, httptest.NewRequest("GET", "/", nil))
+	if len(nonce) != 24 {
+		t.Fatalf("nonce %q", nonce)
+	}
+	want := "default-src 'self'; script-src 'self' 'nonce-" + nonce + "' 'strict-dynamic'"
+	if got := w.Header().Get("Content-Security-Policy"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	// Every request gets a fresh nonce
+	if _, next := serve(s, httptest.NewRequest("GET", "/", nil)); next == nonce {
+		t.Fatal("nonce reused")
+	}
+	// Policies without nonce source do not generate one
+	s = New(Options{ContentPolicy: NewPolicy().Add("default-src", None)})
+	if _, nonce := serve(s, httptest.NewRequest("GET", "/", nil)); nonce != "" {
+		t.Fatalf("got nonce %q", nonce)
+	}
+}
+
+func TestContentPolicyReportOnly(t *testing.T) {
+	p := NewPolicy().Add("default-src", Self).ReportTo("/csp")
+	p.ReportOnly = true
+	w, _ := serve(New(Options{ContentPolicy: p}), httptest.NewRequest("GET", "/", nil))
+	h := w.Header()
+	if h.Get("Content-Security-Policy") != "" {
+		t.Fatal("report only policy enforced")
+	}
+	if got := h.Get("Content-Security-Policy-Report-Only"); got != "default-src 'self'; report-uri /csp; report-to csp-endpoint" {
+		t.Fatalf("got %q", got)
+	}
+	if got := h.Get("Reporting-Endpoints"); got != `csp-endpoint="/csp"` {
+		t.Fatalf("got Reporting-Endpoints %q", got)
+	}
+}
+
+func TestCSPNonceFunc(t *testing.T) {
+	s := New(Options{ContentPolicy: NewPolicy().Add("script-src", Nonce)})
+	var got, want string
+	h := route.MiddlewareRunner{
+		Stack: []route.Middleware{s},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = RequestFuncMap()["cspNonce"](r).(func() string)()
+			want = GetNonce(r)
+		}),
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got == "" || got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	var reports []Report
+	h := NewReportHandler(func(r Report) {
+		reports = append(reports, r)
+	})
+	tests := []struct {
+		contentType, body string
+		code              int
+		want              []Report
+	}{
+		{
+			"application/csp-report",
+			`{"csp-report":{"document-uri":"https://a.example/p","blocked-uri":"inline","violated-directive":"script-src-elem","line-number":3,"status-code":200}}`,
+			204,
+			[]Report{{DocumentURI: "https://a.example/p", BlockedURI: "inline", EffectiveDirective: "script-src-elem", LineNumber: 3, StatusCode: 200, UserAgent: "test"}},
+		},
+		{
+			"application/reports+json",
+			`[{"type":"csp-violation","user_agent":"browser","body":{"documentURL":"https://a.example/","blockedURL":"https://cdn.example/x.js","effectiveDirective":"script-src","disposition":"report","columnNumber":9}},{"type":"deprecation","body":{}}]`,
+			204,
+			[]Report{{DocumentURI: "https://a.example/", BlockedURI: "https://cdn.example/x.js", EffectiveDirective: "script-src", Disposition: "report", ColumnNumber: 9, UserAgent: "browser"}},
+		},
+		{"application/csp-report", `{"csp-report":`, 400, nil},
+		{"application/reports+json", `{}`, 400, nil},
+	}
+	for _, tt := range tests {
+		reports = nil
+		r := httptest.NewRequest("POST", "/csp", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		r.Header.Set("User-Agent", "test")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.body, w.Code, tt.code)
+		}
+		if len(reports) != len(tt.want) {
+			t.Errorf("%s: got reports %+v", tt.body, reports)
+			continue
+		}
+		for i := range reports {
+			if reports[i] != tt.want[i] {
+				t.Errorf("%s: got %+v, want %+v", tt.body, reports[i], tt.want[i])
+			}
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/csp", nil))
+	if w.Code != 405 || w.Header().Get("Allow") != "POST" {
+		t.Fatalf("GET got %d", w.Code)
+	}
+}
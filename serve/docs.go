@@ -5,6 +5,33 @@
 /*
 Package serve wraps standard HTTP server with easy-to-use global API,
 embedded Gorilla Mux router, graceful shutdown, and request body limiter.
+
+Servers protect themselves from traffic spikes with MaxInFlight, MaxQueue,
+and MaxConns options. Requests above the in-flight limit wait in a queue
+ordered by priority, and are shed with 503 and Retry-After once the queue is
+full or QueueTimeout passes. TargetLatency lowers the in-flight limit while
+average latency stays above target. Priorities are given by the Classifier,
+such as route.Router with priorities set by Route.Priority.
+
+The H2C option accepts HTTP/2 without TLS, so that gRPC calls are served on
+the same port by the handler set with UseGRPC while other requests go through
+the router.
+
+	serve.WithOptions(serve.Options{Address: ":8080", H2C: true})
+	serve.Use(router).UseGRPC(grpcServer)
+
+Servers with CertFile and KeyFile options, or TLSConfig, serve TLS. The HTTP3
+option also serves HTTP/3 on the UDP port of the same address, advertised to
+HTTP/1 and HTTP/2 clients with Alt-Svc header. Both listeners share the
+handler and shut down together.
+
+Group runs several servers of the same process, such as public and admin
+servers. It catches interrupt signal once and shuts the servers down in
+order, so that the public server drains before the admin server stops.
+
+	g := serve.NewGroup().Add("public", public).Add("admin", admin)
+	g.Order = []string{"public", "admin"}
+	err := g.Run()
 */
 package serve
 
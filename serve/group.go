@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Group runs named servers together, such as public and admin servers of
+// the same process.
+type Group struct {
+	// Order lists names of servers to shut down first, the rest shut down
+	// in reverse order of Add
+	Order   []string
+	mu      sync.Mutex
+	names   []string
+	servers []*Server
+	stop    chan os.Signal
+}
+
+// NewGroup creates empty server group.
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// Add adds the server to the group, replacing server of the same name.
+func (g *Group) Add(name string, s *Server) *Group {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, n := range g.names {
+		if n == name {
+			g.servers[i] = s
+			return g
+		}
+	}
+	g.names = append(g.names, name)
+	g.servers = append(g.servers, s)
+	return g
+}
+
+// Get gets server of the name, or nil if it is not in the group.
+func (g *Group) Get(name string) *Server {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, n := range g.names {
+		if n == name {
+			return g.servers[i]
+		}
+	}
+	return nil
+}
+
+// Run starts servers in order of Add and waits until explicit Stop()
+// function, interrupt signal, or any server exits. Servers are then shut
+// down one by one in shutdown order. Errors of the servers are combined and
+// prefixed by the server name.
+func (g *Group) Run() error {
+	g.mu.Lock()
+	if g.stop != nil {
+		g.mu.Unlock()
+		return ErrServerRunning
+	}
+	stop := make(chan os.Signal, 1)
+	g.stop = stop
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	names := append([]string(nil), g.names...)
+	servers := append([]*Server(nil), g.servers...)
+	g.mu.Unlock()
+	defer g.Stop()
+
+	// Start servers one by one, the rest are not started once a server
+	// fails to listen
+	results := make([]chan error, len(servers))
+	exited := make(chan struct{}, len(servers))
+	started := 0
+	var errs []error
+	for i, s := range servers {
+		ready := make(chan struct{})
+		results[i] = make(chan error, 1)
+		go func(s *Server, result chan<- error) {
+			result <- s.run(false, ready)
+			exited <- struct{}{}
+		}(s, results[i])
+		select {
+		case <-ready:
+			started++
+			continue
+		case err := <-results[i]:
+			errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+		}
+		break
+	}
+	// Wait until stop signal is closed or triggered, or any server exits
+	if len(errs) == 0 {
+		select {
+		case <-stop:
+		case <-exited:
+		}
+	}
+	for _, i := range g.shutdownOrder(names[:started]) {
+		servers[i].Stop()
+		if err := <-results[i]; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownOrder gets indexes of the names in shutdown order.
+func (g *Group) shutdownOrder(names []string) []int {
+	done := make([]bool, len(names))
+	order := make([]int, 0, len(names))
+	for _, o := range g.Order {
+		for i, n := range names {
+			if n == o && !done[i] {
+				done[i] = true
+				order = append(order, i)
+			}
+		}
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		if !done[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// Stop servers of the group without waiting for interrupt signal
+func (g *Group) Stop() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop == nil {
+		return ErrServerStopped
+	}
+	signal.Stop(g.stop)
+	close(g.stop)
+	g.stop = nil
+	return nil
+}
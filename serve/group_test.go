@@ -0,0 +1,158 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shutdownLog records shutdown of tracked connections by server name
+type shutdownLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// conn is tracked connection of the named server
+type conn struct {
+	log  *shutdownLog
+	name string
+}
+
+func (c conn) Shutdown(ctx context.Context) error {
+	c.log.mu.Lock()
+	c.log.names = append(c.log.names, c.name)
+	c.log.mu.Unlock()
+	return nil
+}
+
+// trackedServer creates server tracking a connection of each request.
+func trackedServer(t *testing.T, log *shutdownLog, name string) (*Server, string) {
+	addr := freeAddr(t)
+	s := NewServer().WithOptions(Options{Address: addr}).Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Track(r, conn{log, name})
+	}))
+	return s, addr
+}
+
+// waitListening waits until the address accepts connections.
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if c, err := net.Dial("tcp", addr); err == nil {
+			c.Close()
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s is not listening", addr)
+}
+
+func TestGroupShutdownOrder(t *testing.T) {
+	log := &shutdownLog{}
+	g := NewGroup()
+	g.Order = []string{"b"}
+	var addrs []string
+	for _, name := range []string{"a", "b", "c"} {
+		s, addr := trackedServer(t, log, name)
+		g.Add(name, s)
+		addrs = append(addrs, addr)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- g.Run()
+	}()
+	for _, addr := range addrs {
+		waitListening(t, addr)
+		res, err := http.Get("http://" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if err := g.Run(); err != ErrServerRunning {
+		t.Fatalf("second run got %v", err)
+	}
+	if err := g.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	// Ordered servers shut down first, the rest in reverse order of Add
+	if got := strings.Join(log.names, ","); got != "b,c,a" {
+		t.Fatalf("shutdown order %s", got)
+	}
+	if err := g.Stop(); err != ErrServerStopped {
+		t.Fatalf("second stop got %v", err)
+	}
+}
+
+func TestGroupListenFailure(t *testing.T) {
+	log := &shutdownLog{}
+	a, addr := trackedServer(t, log, "a")
+	c, caddr := trackedServer(t, log, "c")
+	ln, err := net.Listen("tcp", freeAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	b := NewServer().WithOptions(Options{Address: ln.Addr().String()})
+	g := NewGroup().Add("a", a).Add("b", b).Add("c", c)
+
+	// The first server is stopped once the second fails to listen, the
+	// third is never started
+	err = g.Run()
+	if err == nil || !strings.HasPrefix(err.Error(), "b: ") {
+		t.Fatalf("got %v", err)
+	}
+	for _, addr := range []string{addr, caddr} {
+		if c, err := net.Dial("tcp", addr); err == nil {
+			c.Close()
+			t.Fatalf("%s is still listening", addr)
+		}
+	}
+	if a.Stop() != ErrServerStopped || c.Stop() != ErrServerStopped {
+		t.Fatal("server still running")
+	}
+}
+
+func TestGroupServerExit(t *testing.T) {
+	log := &shutdownLog{}
+	a, addr := trackedServer(t, log, "a")
+	b, baddr := trackedServer(t, log, "b")
+	g := NewGroup().Add("a", a).Add("b", b)
+	result := make(chan error, 1)
+	go func() {
+		result <- g.Run()
+	}()
+	waitListening(t, addr)
+	waitListening(t, baddr)
+	res, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	// Stopping a member server shuts down the whole group
+	if err := g.Get("b").Stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("group did not shut down")
+	}
+	if got := strings.Join(log.names, ","); got != "a" {
+		t.Fatalf("shutdown order %s", got)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cHandler wraps the server handler to accept HTTP/2 without TLS, both
+// with prior knowledge and with HTTP/1.1 Upgrade. The HTTP/2 server is
+// configured on the http.Server so that its connections drain on shutdown.
+func (h *Server) h2cHandler(hs *http.Server) (http.Handler, error) {
+	h2s := &http2.Server{IdleTimeout: h.IdleTimeout}
+	if err := http2.ConfigureServer(hs, h2s); err != nil {
+		return nil, err
+	}
+	return h2c.NewHandler(hs.Handler, h2s), nil
+}
+
+// isGRPC checks whether the request is gRPC call.
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// UseGRPC set handler of gRPC calls, such as grpc.Server, served on the same
+// port as the handler. gRPC calls require HTTP/2, so enable H2C option on
+// servers without TLS.
+func (h *Server) UseGRPC(handler http.Handler) *Server {
+	h.GRPC = handler
+	return h
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// h2cServer starts H2C server whose handlers respond with their name and
+// the request protocol.
+func h2cServer(t *testing.T) string {
+	addr := freeAddr(t)
+	s := NewServer().WithOptions(Options{Address: addr, H2C: true})
+	s.Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "http "+r.Proto)
+	}))
+	s.UseGRPC(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		io.WriteString(w, "grpc "+r.Proto)
+	}))
+	start(t, s)
+	return addr
+}
+
+// get sends request with the content type and gets the response body.
+func get(t *testing.T, c *http.Client, url, contentType string) string {
+	t.Helper()
+	req, _ := http.NewRequest("POST", url, strings.NewReader("data"))
+	req.Header.Set("Content-Type", contentType)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	return string(b)
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	addr := h2cServer(t)
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	defer tr.CloseIdleConnections()
+	c := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+	if body := get(t, c, "http://"+addr+"/", "text/plain"); body != "http HTTP/2.0" {
+		t.Fatalf("got %q", body)
+	}
+	// gRPC calls are dispatched to the gRPC handler
+	if body := get(t, c, "http://"+addr+"/pkg.Service/Method", "application/grpc+proto"); body != "grpc HTTP/2.0" {
+		t.Fatalf("gRPC got %q", body)
+	}
+	// gRPC calls over HTTP/1.1 are not dispatched
+	if body := get(t, &http.Client{Timeout: 5 * time.Second}, "http://"+addr+"/", "application/grpc"); body != "http HTTP/1.1" {
+		t.Fatalf("HTTP/1.1 got %q", body)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	addr := h2cServer(t)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(c, "GET / HTTP/1.1\r\nHost: "+addr+"\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n")
+	br := bufio.NewReader(c)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("got %s upgrade %q", res.Status, res.Header.Get("Upgrade"))
+	}
+	// The server responds the upgrade request on stream 1
+	io.WriteString(c, http2.ClientPreface)
+	fr := http2.NewFramer(c, br)
+	if err := fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	// The upgrade request keeps its HTTP/1.1 protocol, the next request
+	// is sent as HTTP/2 stream
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range [][2]string{{":method", "GET"}, {":scheme", "http"}, {":authority", addr}, {":path", "/"}} {
+		enc.WriteField(hpack.HeaderField{Name: f[0], Value: f[1]})
+	}
+	err = fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 3, BlockFragment: block.Bytes(), EndStream: true, EndHeaders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := map[uint32]string{}
+	body := map[uint32]string{}
+	var stream uint32
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == ":status" {
+			status[stream] = f.Value
+		}
+	})
+	for ended := 0; ended < 2; {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream = f.Header().StreamID
+		switch f := f.(type) {
+		case *http2.HeadersFrame:
+			dec.Write(f.HeaderBlockFragment())
+		case *http2.DataFrame:
+			body[stream] += string(f.Data())
+		default:
+			continue
+		}
+		if f.Header().Flags.Has(http2.FlagDataEndStream) {
+			ended++
+		}
+	}
+	if status[1] != "200" || body[1] != "http HTTP/1.1" {
+		t.Fatalf("upgrade request got %s %q", status[1], body[1])
+	}
+	if status[3] != "200" || body[3] != "http HTTP/2.0" {
+		t.Fatalf("got %s %q", status[3], body[3])
+	}
+}
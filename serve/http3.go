@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// ErrNoTLS represents HTTP/3 option set on server without TLS
+var ErrNoTLS = errors.New("omnibus-server: HTTP/3 requires TLS")
+
+// tlsConfig gets TLS configuration of the server, or nil for server without
+// TLS. Certificate files are loaded if the server has no TLSConfig.
+func (h *Server) tlsConfig() (*tls.Config, error) {
+	if h.TLSConfig != nil {
+		return h.TLSConfig.Clone(), nil
+	}
+	if h.CertFile == "" && h.KeyFile == "" {
+		if h.HTTP3 {
+			return nil, ErrNoTLS
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(h.CertFile, h.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// listenHTTP3 opens UDP listener on the same address as the TCP listener,
+// so that Alt-Svc advertises the port of the TCP listener.
+func (h *Server) listenHTTP3(ln net.Listener, conf *tls.Config) (*http3.Server, net.PacketConn, error) {
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &http3.Server{
+		Handler:        h,
+		TLSConfig:      http3.ConfigureTLSConfig(conf),
+		MaxHeaderBytes: h.MaxHeaderBytes,
+		IdleTimeout:    h.IdleTimeout,
+	}
+	return s, pc, nil
+}
+
+// altSvc advertises HTTP/3 listener on responses of HTTP/1 and HTTP/2.
+func (h *Server) altSvc(w http.ResponseWriter, r *http.Request) {
+	if s := h.quic.Load(); s != nil && r.ProtoMajor < 3 {
+		// Alt-Svc is unavailable until the listener is being served
+		s.SetQUICHeaders(w.Header())
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// testCert creates self-signed certificate of the loopback address.
+func testCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// freeAddr gets loopback address of an unused port.
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// start runs the server until the test ends.
+func start(t *testing.T, s *Server) {
+	ready := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- s.run(false, ready)
+	}()
+	select {
+	case <-ready:
+	case err := <-result:
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Stop()
+		if err := <-result; err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestHTTP3(t *testing.T) {
+	addr := freeAddr(t)
+	s := NewServer().WithOptions(Options{
+		Address: addr,
+		HTTP3:   true,
+	}).Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{testCert(t)}}
+	start(t, s)
+	conf := &tls.Config{InsecureSkipVerify: true}
+
+	// HTTP/1 and HTTP/2 responses advertise the UDP port
+	tcp := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+	res, err := tcp.Get("https://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	_, port, _ := net.SplitHostPort(addr)
+	if alt := res.Header.Get("Alt-Svc"); !strings.Contains(alt, `h3=":`+port+`"`) {
+		t.Fatalf("Alt-Svc %q", alt)
+	}
+
+	h3 := &http3.Transport{TLSClientConfig: conf}
+	defer h3.Close()
+	client := &http.Client{Transport: h3, Timeout: 5 * time.Second}
+	for i := 0; i < 3; i++ {
+		res, err := client.Get("https://" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(b) != "HTTP/3.0" || res.Header.Get("Alt-Svc") != "" {
+			t.Fatalf("got %q with Alt-Svc %q", b, res.Header.Get("Alt-Svc"))
+		}
+	}
+}
+
+func TestHTTP3NoTLS(t *testing.T) {
+	s := NewServer().WithOptions(Options{Address: freeAddr(t), HTTP3: true})
+	if err := s.run(false, nil); err != ErrNoTLS {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Classifier gets priority class of requests. Requests of higher priority
+// are admitted first when the server is overloaded, and negative priority
+// requests are shed first. Requests are classified only once they have to
+// wait for a slot. route.Router implements this interface.
+type Classifier interface {
+	PriorityOf(r *http.Request) int
+}
+
+// Load store admission state of the server
+type Load struct {
+	InFlight int
+	Limit    int
+	Queued   int
+	Shed     int64
+	Latency  time.Duration
+}
+
+// limiter admits in-flight requests up to the adaptive limit and queues the
+// rest by priority
+type limiter struct {
+	mu       sync.Mutex
+	max      int
+	limit    int
+	active   int
+	queue    []*waiter
+	maxQueue int
+	timeout  time.Duration
+	target   time.Duration
+	latency  float64
+	adjusted time.Time
+	shed     int64
+}
+
+// waiter store queued request admission
+type waiter struct {
+	priority int
+	ready    chan bool
+}
+
+// newLimiter creates limiter from server options.
+func newLimiter(o Options) *limiter {
+	return &limiter{
+		max:      o.MaxInFlight,
+		limit:    o.MaxInFlight,
+		maxQueue: o.MaxQueue,
+		timeout:  o.QueueTimeout,
+		target:   o.TargetLatency,
+	}
+}
+
+// acquire waits for a request slot, it returns false if the request is
+// shed. The request is classified only if it has to wait.
+func (l *limiter) acquire(ctx context.Context, classify func() int) bool {
+	if l.admit() {
+		return true
+	}
+	// Classify outside the lock, a slot may be freed meanwhile
+	priority := classify()
+	l.mu.Lock()
+	if l.active < l.limit && len(l.queue) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return true
+	}
+	if len(l.queue) >= l.maxQueue {
+		// Evict the lowest priority waiter in favor of higher priority
+		last := len(l.queue) - 1
+		if last < 0 || l.queue[last].priority >= priority {
+			l.shed++
+			l.mu.Unlock()
+			return false
+		}
+		l.queue[last].ready <- false
+		l.queue = l.queue[:last]
+		l.shed++
+	}
+	w := &waiter{priority: priority, ready: make(chan bool, 1)}
+	l.insert(w)
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case ok := <-w.ready:
+		return ok
+	case <-expired:
+	case <-ctx.Done():
+	}
+	// Give up waiting unless the slot is already granted
+	l.mu.Lock()
+	removed := l.remove(w)
+	if removed {
+		l.shed++
+	}
+	l.mu.Unlock()
+	if !removed && <-w.ready {
+		l.release(0)
+	}
+	return false
+}
+
+// admit takes a free slot without waiting, it returns false if the
+// request has to wait.
+func (l *limiter) admit() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active < l.limit && len(l.queue) == 0 {
+		l.active++
+		return true
+	}
+	return false
+}
+
+// release frees a request slot, samples its latency and admits queued
+// requests.
+func (l *limiter) release(elapsed time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if elapsed > 0 && l.target > 0 {
+		l.adapt(elapsed)
+	}
+	for l.active < l.limit && len(l.queue) > 0 {
+		w := l.queue[0]
+		l.queue = l.queue[1:]
+		l.active++
+		w.ready <- true
+	}
+}
+
+// adapt updates latency average and adjusts the limit, decreasing it
+// multiplicatively above target latency and increasing it additively below.
+func (l *limiter) adapt(elapsed time.Duration) {
+	if l.latency == 0 {
+		l.latency = float64(elapsed)
+	} else {
+		l.latency += 0.1 * (float64(elapsed) - l.latency)
+	}
+	now := time.Now()
+	if now.Sub(l.adjusted) < 100*time.Millisecond {
+		return
+	}
+	l.adjusted = now
+	if time.Duration(l.latency) > l.target {
+		if l.limit = l.limit * 9 / 10; l.limit < 1 {
+			l.limit = 1
+		}
+	} else if l.limit < l.max {
+		l.limit++
+	}
+}
+
+// insert adds waiter after waiters of the same or higher priority.
+func (l *limiter) insert(w *waiter) {
+	i := len(l.queue)
+	for i > 0 && l.queue[i-1].priority < w.priority {
+		i--
+	}
+	l.queue = append(l.queue, nil)
+	copy(l.queue[i+1:], l.queue[i:])
+	l.queue[i] = w
+}
+
+// remove removes waiter from the queue, it returns false if the waiter
+// is not queued anymore.
+func (l *limiter) remove(w *waiter) bool {
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// slot store request slot of the limiter, released once
+type slot struct {
+	l     *limiter
+	start time.Time
+	done  int32
+}
+
+// release frees the slot, sampling its latency unless the request turned
+// into a long-lived connection.
+func (s *slot) release(sample bool) {
+	if !atomic.CompareAndSwapInt32(&s.done, 0, 1) {
+		return
+	}
+	var elapsed time.Duration
+	if sample {
+		elapsed = time.Since(s.start)
+	}
+	s.l.release(elapsed)
+}
+
+// load gets current admission state.
+func (l *limiter) load() Load {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Load{
+		InFlight: l.active,
+		Limit:    l.limit,
+		Queued:   len(l.queue),
+		Shed:     l.shed,
+		Latency:  time.Duration(l.latency),
+	}
+}
+
+// retryAfter formats Retry-After header value in seconds.
+func retryAfter(d time.Duration) string {
+	if d <= 0 {
+		d = time.Second
+	}
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// shedResponse writes 503 response of shed requests.
+func shedResponse(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", retryAfter(d))
+	http.Error(w, http.StatusText(503), 503)
+}
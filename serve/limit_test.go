@@ -0,0 +1,229 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// priority gets classify function of the priority.
+func priority(p int) func() int {
+	return func() int { return p }
+}
+
+// waitQueued waits until the limiter has n queued requests.
+func waitQueued(t *testing.T, l *limiter, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if l.load().Queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queued %d, want %d", l.load().Queued, n)
+}
+
+func TestLimiterClassifyOnWait(t *testing.T) {
+	l := newLimiter(Options{MaxInFlight: 1, MaxQueue: 1})
+	classified := false
+	classify := func() int {
+		classified = true
+		return 0
+	}
+	if !l.acquire(context.Background(), classify) || classified {
+		t.Fatalf("free slot: classified %v", classified)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if l.acquire(ctx, classify) || !classified {
+		t.Fatalf("busy slot: classified %v", classified)
+	}
+	if load := l.load(); load.InFlight != 1 || load.Queued != 0 || load.Shed != 1 {
+		t.Fatalf("load %+v", load)
+	}
+}
+
+func TestLimiterQueuePriority(t *testing.T) {
+	l := newLimiter(Options{MaxInFlight: 1, MaxQueue: 3})
+	l.acquire(context.Background(), priority(0))
+	order := make(chan int, 3)
+	for i, p := range []int{0, 5, 1} {
+		go func(p int) {
+			if l.acquire(context.Background(), priority(p)) {
+				order <- p
+			}
+		}(p)
+		waitQueued(t, l, i+1)
+	}
+	for _, want := range []int{5, 1, 0} {
+		l.release(0)
+		if got := <-order; got != want {
+			t.Fatalf("admitted priority %d, want %d", got, want)
+		}
+	}
+	l.release(0)
+	if load := l.load(); load.InFlight != 0 || load.Queued != 0 {
+		t.Fatalf("load %+v", load)
+	}
+}
+
+func TestLimiterShed(t *testing.T) {
+	l := newLimiter(Options{MaxInFlight: 1, MaxQueue: 1})
+	l.acquire(context.Background(), priority(0))
+	result := make(chan bool, 1)
+	go func() {
+		result <- l.acquire(context.Background(), priority(0))
+	}()
+	waitQueued(t, l, 1)
+	// Full queue sheds requests of the same or lower priority
+	if l.acquire(context.Background(), priority(0)) {
+		t.Fatal("same priority request admitted")
+	}
+	// Higher priority request evicts the queued one
+	admitted := make(chan bool, 1)
+	go func() {
+		admitted <- l.acquire(context.Background(), priority(1))
+	}()
+	if <-result {
+		t.Fatal("evicted request admitted")
+	}
+	waitQueued(t, l, 1)
+	l.release(0)
+	if !<-admitted {
+		t.Fatal("higher priority request shed")
+	}
+	if load := l.load(); load.Shed != 2 {
+		t.Fatalf("load %+v", load)
+	}
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	l := newLimiter(Options{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	l.acquire(context.Background(), priority(0))
+	if l.acquire(context.Background(), priority(0)) {
+		t.Fatal("request admitted after timeout")
+	}
+	if load := l.load(); load.Queued != 0 || load.Shed != 1 {
+		t.Fatalf("load %+v", load)
+	}
+}
+
+func TestLimitListener(t *testing.T) {
+	for _, secure := range []bool{false, true} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ll := &limitListener{Listener: ln, max: 1, retryAfter: 2 * time.Second, tls: secure}
+		go func() {
+			for {
+				if _, err := ll.Accept(); err != nil {
+					return
+				}
+			}
+		}()
+		first, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		second.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b, err := io.ReadAll(second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secure && len(b) != 0 {
+			t.Errorf("TLS listener wrote %q", b)
+		}
+		if !secure && (!strings.HasPrefix(string(b), "HTTP/1.1 503 ") || !strings.Contains(string(b), "Retry-After: 2\r\n")) {
+			t.Errorf("plaintext listener wrote %q", b)
+		}
+		first.Close()
+		second.Close()
+		ln.Close()
+	}
+}
+
+func TestLimitListenerTLS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ll := &limitListener{Listener: ln, max: 0, tls: true}
+	go func() {
+		for {
+			if _, err := ll.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+	// Shed TLS clients see a failed handshake rather than garbage bytes
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	_, err = client.Get("https://" + ln.Addr().String())
+	if err == nil || strings.Contains(err.Error(), "HTTP response") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// stream is tracked connection of the test
+type stream struct{}
+
+func (s *stream) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestLimiterTrackedStream(t *testing.T) {
+	open := make(chan struct{})
+	s := NewServer().WithOptions(Options{MaxInFlight: 2, TargetLatency: 10 * time.Millisecond})
+	s.Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			untrack := Track(r, &stream{})
+			defer untrack()
+			<-open
+		}
+	}))
+	s.limiter.Store(newLimiter(s.Options))
+	var streams sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil))
+		}()
+	}
+	// Open streams do not hold slots of normal requests
+	for i := 0; i < 1000 && s.Conns() < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 200 {
+			t.Fatalf("request %d got %d", i, w.Code)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+	close(open)
+	streams.Wait()
+	// Stream duration is not sampled as request latency
+	if load := s.Load(); load.InFlight != 0 || load.Limit != 2 || load.Shed != 0 {
+		t.Fatalf("load %+v", load)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// limitListener sheds connections above the open connection limit
+type limitListener struct {
+	net.Listener
+	mu         sync.Mutex
+	open       int
+	max        int
+	retryAfter time.Duration
+	// tls listeners close excess connections without response, as the
+	// client expects TLS handshake
+	tls bool
+}
+
+// Accept accepts connections below the limit, excess connections receive
+// 503 response on plaintext listener and are closed.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		if l.open >= l.max {
+			l.mu.Unlock()
+			go l.reject(conn)
+			continue
+		}
+		l.open++
+		l.mu.Unlock()
+		return &limitConn{Conn: conn, release: l.release}, nil
+	}
+}
+
+// release decrements open connection count.
+func (l *limitListener) release() {
+	l.mu.Lock()
+	l.open--
+	l.mu.Unlock()
+}
+
+// reject writes minimal 503 response to the connection and closes it.
+func (l *limitListener) reject(conn net.Conn) {
+	if l.tls {
+		conn.Close()
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	conn.Write([]byte("HTTP/1.1 503 Service Unavailable\r\n" +
+		"Retry-After: " + retryAfter(l.retryAfter) + "\r\n" +
+		"Connection: close\r\nContent-Length: 0\r\n\r\n"))
+	conn.Close()
+}
+
+// limitConn releases its slot once closed
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+// Close closes the connection and releases its slot.
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -6,13 +6,19 @@ package serve
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/mandala/omnibus/realip"
+	"github.com/quic-go/quic-go/http3"
 )
 
 // ErrServerRunning represents unavailable action on running server
@@ -33,15 +39,54 @@ type Options struct {
 	ShutdownTimeout time.Duration
 	MaxHeaderBytes  int
 	MaxBytes        int64
+	// MaxInFlight limits concurrent requests, excess requests wait in the
+	// queue or are shed. Connections registered with Track do not count.
+	MaxInFlight int
+	// MaxQueue limits requests waiting for a slot
+	MaxQueue int
+	// QueueTimeout limits time of requests waiting for a slot
+	QueueTimeout time.Duration
+	// TargetLatency adapts the in-flight limit to keep average latency
+	// below target
+	TargetLatency time.Duration
+	// MaxConns limits open connections, excess connections are closed after
+	// 503 response, or right away on TLS listener
+	MaxConns int
+	// RetryAfter is suggested to shed clients, defaults to one second
+	RetryAfter time.Duration
+	// ProxyProtocol lists CIDRs of load balancers allowed to send PROXY
+	// protocol header
+	ProxyProtocol []string
+	// H2C accepts HTTP/2 without TLS, with prior knowledge or HTTP/1.1
+	// Upgrade
+	H2C bool
+	// CertFile and KeyFile serve TLS if the server has no TLSConfig
+	CertFile string
+	KeyFile  string
+	// HTTP3 serves QUIC on the UDP port of the TLS listener and advertises
+	// it with Alt-Svc
+	HTTP3 bool
 }
 
 // Server store server handle state
 type Server struct {
 	Options
 	Handler http.Handler
-	mu      sync.Mutex
-	server  *http.Server
-	stop    chan os.Signal
+	// Classifier gets priority of requests, defaults to the handler if it
+	// implements serve.Classifier
+	Classifier Classifier
+	// GRPC handles gRPC calls instead of the handler
+	GRPC http.Handler
+	// TLSConfig serves TLS, certificates are loaded from CertFile and
+	// KeyFile options if empty
+	TLSConfig *tls.Config
+	mu        sync.Mutex
+	server    *http.Server
+	stop      chan os.Signal
+	tracker   tracker
+	// limiter and quic are read by handlers without the lock
+	limiter atomic.Pointer[limiter]
+	quic    atomic.Pointer[http3.Server]
 }
 
 // WithOptions set handle configurations
@@ -58,6 +103,12 @@ func (h *Server) Use(handler http.Handler) *Server {
 
 // Run server and wait until explicit Stop() function or interrupt signal
 func (h *Server) Run() error {
+	return h.run(true, nil)
+}
+
+// run serves until the server is stopped, and also on interrupt signal if
+// signals is set. The ready channel is closed once the server is listening.
+func (h *Server) run(signals bool, ready chan<- struct{}) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	// Do not continue if server already running
@@ -73,26 +124,110 @@ func (h *Server) Run() error {
 		ReadTimeout:       h.ConnTimeout,
 		WriteTimeout:      h.ConnTimeout,
 	}
+	conf, err := h.tlsConfig()
+	if err != nil {
+		h.server = nil
+		return err
+	}
+	h.server.TLSConfig = conf
+	if h.H2C {
+		handler, err := h.h2cHandler(h.server)
+		if err != nil {
+			h.server = nil
+			return err
+		}
+		h.server.Handler = handler
+	}
+	// Track connections of this run
+	h.tracker.reset()
+	// Initialize request limiter
+	if h.MaxInFlight > 0 {
+		h.limiter.Store(newLimiter(h.Options))
+	}
 	// Deallocate server handle on function exit
 	defer func() {
 		h.server = nil
+		h.limiter.Store(nil)
+		h.quic.Store(nil)
 	}()
-	// Run http.Server in separate goroutine and initialize error channel
-	errChan := make(chan error)
+	// Open listener and limit its connections if MaxConns option set
+	address := h.Address
+	if address == "" {
+		address = ":http"
+		if conf != nil {
+			address = ":https"
+		}
+	}
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	// Open UDP listener before the TCP listener is wrapped
+	var pc net.PacketConn
+	var quic *http3.Server
+	if h.HTTP3 {
+		quic, pc, err = h.listenHTTP3(ln, conf)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		h.quic.Store(quic)
+	}
+	if len(h.ProxyProtocol) > 0 {
+		pl, err := realip.NewListener(ln, h.ProxyProtocol)
+		if err != nil {
+			ln.Close()
+			if pc != nil {
+				pc.Close()
+			}
+			return err
+		}
+		ln = pl
+	}
+	if h.MaxConns > 0 {
+		ln = &limitListener{Listener: ln, max: h.MaxConns, retryAfter: h.RetryAfter, tls: conf != nil}
+	}
+	// Run http.Server and HTTP/3 server in separate goroutines and
+	// initialize error channel
+	errChan := make(chan error, 2)
 	go func() {
-		if err := h.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if conf != nil {
+			err = h.server.ServeTLS(ln, "", "")
+		} else {
+			err = h.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- err
 			return
 		}
 		errChan <- nil
 	}()
+	if pc != nil {
+		defer pc.Close()
+		go func() {
+			if err := quic.Serve(pc); err != nil && err != http.ErrServerClosed {
+				errChan <- err
+				return
+			}
+			errChan <- nil
+		}()
+	}
 	// Initialize stop signal catcher
-	h.stop = make(chan os.Signal)
-	signal.Notify(h.stop, syscall.SIGTERM, syscall.SIGINT)
+	stop := make(chan os.Signal, 1)
+	h.stop = stop
+	if signals {
+		signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	}
+	if ready != nil {
+		close(ready)
+	}
 	// Wait until stop signal is closed or triggered
 	h.mu.Unlock()
-	<-h.stop
+	<-stop
 	h.mu.Lock()
+	signal.Stop(stop)
+	h.stop = nil
 	// Create context for shutdown process
 	ctx := context.Background()
 	if h.ShutdownTimeout > 0 {
@@ -100,35 +235,81 @@ func (h *Server) Run() error {
 		ctx, cancel = context.WithTimeout(ctx, h.ShutdownTimeout)
 		defer cancel()
 	}
-	// Start shutdown process
-	if err := h.server.Shutdown(ctx); err != nil {
-		// Close server error channel and return shutdown error instead
-		close(errChan)
+	// Start shutdown process, hijacked connections are tracked separately
+	// since http.Server.Shutdown does not wait for them
+	done := make(chan struct{})
+	go func() {
+		h.tracker.shutdown(ctx)
+		close(done)
+	}()
+	h3Err := make(chan error, 1)
+	if pc != nil {
+		go func() {
+			h3Err <- quic.Shutdown(ctx)
+		}()
+	} else {
+		h3Err <- nil
+	}
+	err = h.server.Shutdown(ctx)
+	<-done
+	if qerr := <-h3Err; err == nil {
+		err = qerr
+	}
+	if err != nil {
+		// Return shutdown error instead of serve errors
+		return err
+	}
+	// Return error from Serve of the listeners
+	if err := <-errChan; err != nil {
 		return err
 	}
-	// Return error from listenAndServe
-	return <-errChan
+	if pc != nil {
+		return <-errChan
+	}
+	return nil
 }
 
 // Stop server without waiting for interrupt signal
 func (h *Server) Stop() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if h.server == nil {
+	if h.server == nil || h.stop == nil {
 		return ErrServerStopped
 	}
 	// Stop os.signal notifier before closing the channel
 	signal.Stop(h.stop)
 	close(h.stop)
+	h.stop = nil
 	return nil
 }
 
 // ServeHTTP implements http.Handler for maximum request body handler
 func (h *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Store server handle for connection tracking
+	r = r.WithContext(context.WithValue(r.Context(), serverKey, h))
+	h.altSvc(w, r)
+	// Dispatch gRPC calls, which enforce their own message size and
+	// concurrency limits
+	if h.GRPC != nil && isGRPC(r) {
+		h.GRPC.ServeHTTP(w, r)
+		return
+	}
 	// Limit body io.Reader with http.MaxBytesReader if MaxBytes option set
 	if h.MaxBytes > 0 {
 		r.Body = http.MaxBytesReader(w, r.Body, h.MaxBytes)
 	}
+	// Admit request if MaxInFlight option set
+	if l := h.limiter.Load(); l != nil {
+		if !l.acquire(r.Context(), func() int { return h.priorityOf(r) }) {
+			shedResponse(w, h.RetryAfter)
+			return
+		}
+		// The slot is given up early once the request is tracked as
+		// long-lived connection
+		s := &slot{l: l, start: time.Now()}
+		r = r.WithContext(context.WithValue(r.Context(), slotKey, s))
+		defer s.release(true)
+	}
 	// Run the entrypoint handler
 	if h.Handler != nil {
 		h.Handler.ServeHTTP(w, r)
@@ -139,6 +320,25 @@ func (h *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("404 Not Found"))
 }
 
+// priorityOf gets priority class of the request.
+func (h *Server) priorityOf(r *http.Request) int {
+	if h.Classifier != nil {
+		return h.Classifier.PriorityOf(r)
+	}
+	if c, ok := h.Handler.(Classifier); ok {
+		return c.PriorityOf(r)
+	}
+	return 0
+}
+
+// Load gets admission state of running server with MaxInFlight option.
+func (h *Server) Load() Load {
+	if l := h.limiter.Load(); l != nil {
+		return l.load()
+	}
+	return Load{}
+}
+
 // NewServer create new empty server object
 func NewServer() *Server {
 	return &Server{}
@@ -159,6 +359,11 @@ func Use(handler http.Handler) *Server {
 	return global.Use(handler)
 }
 
+// UseGRPC set global gRPC handler configuration
+func UseGRPC(handler http.Handler) *Server {
+	return global.UseGRPC(handler)
+}
+
 // GetOptions get global configurations
 func GetOptions() *Options {
 	return &global.Options
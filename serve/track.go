@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package serve
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// contextKey define private type for request context keys
+type contextKey int
+
+const (
+	serverKey contextKey = iota
+	slotKey
+)
+
+// Tracked is implemented by long-lived connections that outlive the
+// server shutdown, such as hijacked WebSocket connections. Shutdown must
+// end the connection gracefully and return once it is closed or the
+// context is done.
+type Tracked interface {
+	Shutdown(ctx context.Context) error
+}
+
+// tracker store tracked connections of the server
+type tracker struct {
+	mu       sync.Mutex
+	conns    map[Tracked]struct{}
+	draining bool
+}
+
+// Track registers the connection on the server serving the request, so
+// that the server shuts it down gracefully on stop. The returned function
+// removes the connection, call it once the connection is closed. Requests
+// not served by serve.Server are not tracked. The request gives up its
+// MaxInFlight slot, since the connection may stay open for hours.
+func Track(r *http.Request, c Tracked) func() {
+	h, _ := r.Context().Value(serverKey).(*Server)
+	if h == nil {
+		return func() {}
+	}
+	if s, ok := r.Context().Value(slotKey).(*slot); ok {
+		s.release(false)
+	}
+	t := &h.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		// Connections opened during shutdown are ended right away
+		go c.Shutdown(context.Background())
+		return func() {}
+	}
+	if t.conns == nil {
+		t.conns = make(map[Tracked]struct{})
+	}
+	t.conns[c] = struct{}{}
+	return func() {
+		t.mu.Lock()
+		delete(t.conns, c)
+		t.mu.Unlock()
+	}
+}
+
+// shutdown shuts down tracked connections and waits for them.
+func (t *tracker) shutdown(ctx context.Context) {
+	t.mu.Lock()
+	t.draining = true
+	conns := make([]Tracked, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c Tracked) {
+			defer wg.Done()
+			c.Shutdown(ctx)
+		}(c)
+	}
+	wg.Wait()
+}
+
+// reset allows tracking connections of the next run.
+func (t *tracker) reset() {
+	t.mu.Lock()
+	t.draining = false
+	t.conns = nil
+	t.mu.Unlock()
+}
+
+// Conns gets number of tracked connections.
+func (h *Server) Conns() int {
+	h.tracker.mu.Lock()
+	defer h.tracker.mu.Unlock()
+	return len(h.tracker.conns)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package sse
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Channel broadcasts published events to its streams and keeps bounded
+// history for clients reconnecting with Last-Event-ID.
+type Channel struct {
+	Options
+	mu      sync.Mutex
+	history []Event
+	seq     uint64
+	subs    map[chan Event]struct{}
+}
+
+// NewChannel creates event channel.
+func NewChannel(o Options) *Channel {
+	if o.History <= 0 {
+		o.History = 100
+	}
+	return &Channel{Options: o, subs: make(map[chan Event]struct{})}
+}
+
+// Publish sends the event to all streams of the channel. Events without ID
+// are given sequential ID, the published event is returned.
+func (c *Channel) Publish(e Event) Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	if e.ID == "" {
+		e.ID = strconv.FormatUint(c.seq, 10)
+	}
+	if len(c.history) >= c.History {
+		copy(c.history, c.history[1:])
+		c.history = c.history[:len(c.history)-1]
+	}
+	c.history = append(c.history, e)
+	for ch := range c.subs {
+		select {
+		case ch <- e:
+		default:
+			// Slow streams are ended, the client reconnects and replays
+			// missed events from the history
+			delete(c.subs, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// subscribe gets events published after the last event ID and channel of
+// the next events. Without last event ID or once it is no longer in the
+// history, no events are replayed.
+func (c *Channel) subscribe(lastID string) ([]Event, chan Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var missed []Event
+	if lastID != "" {
+		for i := len(c.history) - 1; i >= 0; i-- {
+			if c.history[i].ID == lastID {
+				missed = append(missed, c.history[i+1:]...)
+				break
+			}
+		}
+	}
+	ch := make(chan Event, 64)
+	c.subs[ch] = struct{}{}
+	return missed, ch
+}
+
+// unsubscribe removes the channel of the stream.
+func (c *Channel) unsubscribe(ch chan Event) {
+	c.mu.Lock()
+	if _, ok := c.subs[ch]; ok {
+		delete(c.subs, ch)
+		close(ch)
+	}
+	c.mu.Unlock()
+}
+
+// Len gets number of streams of the channel.
+func (c *Channel) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subs)
+}
+
+// ServeHTTP implements http.Handler interface.
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, err := NewStream(w, r, c.Options)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	defer s.Close()
+	missed, ch := c.subscribe(LastEventID(r))
+	defer c.unsubscribe(ch)
+	for _, e := range missed {
+		if err := s.Send(e); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := s.Send(e); err != nil {
+				return
+			}
+		case <-s.Done():
+			return
+		}
+	}
+}
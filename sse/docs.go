@@ -0,0 +1,38 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package sse streams Server-Sent Events. Streams format events with id,
+event, data, and retry fields, send heartbeat comments to keep proxies from
+closing idle connections, and flush through middleware response writer
+wrappers. Streams end when the client goes away or serve.Server shuts down.
+
+Channel broadcasts events to its streams and keeps a bounded history, so
+reconnecting clients receive the events they missed since Last-Event-ID.
+
+	updates := sse.NewChannel(sse.Options{History: 100})
+	router.Get("/dashboard/events", updates)
+	updates.Publish(sse.Event{Event: "stats", Data: string(stats)})
+
+Handler streams events of custom sources, such as pubsub subscriptions.
+
+	router.Get("/notifications", &sse.Handler{Serve: func(s *sse.Stream, r *http.Request) {
+		sub := hub.Subscribe(pubsub.Options{}, "user:"+userID(r))
+		defer sub.Close()
+		for {
+			select {
+			case m, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				s.Send(sse.Event{Data: string(m.Data)})
+			case <-s.Done():
+				return
+			}
+		}
+	}})
+*/
+package sse
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,213 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package sse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandala/omnibus/serve"
+)
+
+// ErrNoFlush represents response writer without flush support
+var ErrNoFlush = errors.New("sse: Response writer does not support flushing")
+
+// Event store a single server-sent event
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry tells the client reconnection delay
+	Retry time.Duration
+}
+
+// WriteTo writes the event in text/event-stream format.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + field(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + field(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// field removes line breaks from single line field.
+func field(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "", "\x00", "").Replace(v)
+}
+
+// Options store stream configurations
+type Options struct {
+	// Heartbeat interval of comment lines, defaults to 15 seconds
+	Heartbeat time.Duration
+	// Retry tells clients reconnection delay
+	Retry time.Duration
+	// History size of Channel events kept for replay, defaults to 100
+	History int
+}
+
+// Stream writes events to the client.
+type Stream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	finished chan struct{}
+	once     sync.Once
+	untrack  func()
+}
+
+// NewStream starts event stream response, heartbeats are sent with the
+// interval until the stream is closed.
+func NewStream(w http.ResponseWriter, r *http.Request, o Options) (*Stream, error) {
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	// Flushing writes the header, nothing is written without flush support
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			h.Del("Content-Type")
+			h.Del("Cache-Control")
+			h.Del("X-Accel-Buffering")
+			return nil, ErrNoFlush
+		}
+		return nil, err
+	}
+	// Streams outlive write timeout of the server
+	rc.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &Stream{w: w, rc: rc, ctx: ctx, cancel: cancel, finished: make(chan struct{})}
+	s.untrack = serve.Track(r, s)
+	if o.Retry > 0 {
+		s.write(func() {
+			io.WriteString(w, "retry: "+strconv.FormatInt(o.Retry.Milliseconds(), 10)+"\n\n")
+		})
+	}
+	heartbeat := o.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	go s.heartbeat(heartbeat)
+	return s, nil
+}
+
+// Send writes the event and flushes it to the client.
+func (s *Stream) Send(e Event) error {
+	var err error
+	s.write(func() {
+		_, err = e.WriteTo(s.w)
+	})
+	return err
+}
+
+// Comment writes comment line ignored by clients.
+func (s *Stream) Comment(text string) error {
+	var err error
+	s.write(func() {
+		_, err = io.WriteString(s.w, ": "+field(text)+"\n\n")
+	})
+	return err
+}
+
+// write runs the write function and flushes unless the stream is done.
+func (s *Stream) write(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	f()
+	s.rc.Flush()
+}
+
+// heartbeat sends comments periodically until the stream is done.
+func (s *Stream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.Comment("heartbeat")
+		}
+	}
+}
+
+// Done gets channel closed once the client goes away or the server shuts
+// down.
+func (s *Stream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close ends the stream, call it before the handler returns.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	s.once.Do(func() {
+		close(s.finished)
+		s.untrack()
+	})
+}
+
+// Shutdown ends the stream and waits for the handler to return. It
+// implements serve.Tracked interface.
+func (s *Stream) Shutdown(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler streams events of the serve function.
+type Handler struct {
+	Options
+	// Serve sends events until the stream is done
+	Serve func(*Stream, *http.Request)
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, err := NewStream(w, r, h.Options)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	defer s.Close()
+	h.Serve(s, r)
+}
+
+// LastEventID gets ID of the last event received by reconnecting client.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
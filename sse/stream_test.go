@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readEvents reads n events of the stream response as id:data pairs.
+func readEvents(t *testing.T, sc *bufio.Scanner, n int) []string {
+	t.Helper()
+	var events []string
+	var id string
+	for len(events) < n && sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = line[4:]
+		case strings.HasPrefix(line, "data: "):
+			events = append(events, id+":"+line[6:])
+		}
+	}
+	if len(events) < n {
+		t.Fatalf("got events %v, want %d", events, n)
+	}
+	return events
+}
+
+// connect opens the stream with the last event ID.
+func connect(t *testing.T, url, lastID string) (*http.Response, *bufio.Scanner) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); res.StatusCode != 200 || ct != "text/event-stream" {
+		t.Fatalf("got %d %q", res.StatusCode, ct)
+	}
+	return res, bufio.NewScanner(res.Body)
+}
+
+// waitStreams waits until the channel has n streams.
+func waitStreams(t *testing.T, c *Channel, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if c.Len() == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d streams, want %d", c.Len(), n)
+}
+
+func TestChannelReplay(t *testing.T) {
+	c := NewChannel(Options{History: 3})
+	s := httptest.NewServer(c)
+	defer s.Close()
+	for _, data := range []string{"a", "b", "c", "d"} {
+		c.Publish(Event{Data: data})
+	}
+	// Events after the last ID are replayed before new events
+	res, sc := connect(t, s.URL, "2")
+	waitStreams(t, c, 1)
+	c.Publish(Event{Data: "e"})
+	if got := readEvents(t, sc, 3); strings.Join(got, ",") != "3:c,4:d,5:e" {
+		t.Fatalf("got %v", got)
+	}
+	res.Body.Close()
+	waitStreams(t, c, 0)
+
+	// Events older than the history are not replayed
+	res, sc = connect(t, s.URL, "1")
+	defer res.Body.Close()
+	waitStreams(t, c, 1)
+	c.Publish(Event{ID: "custom", Data: "line1\nline2"})
+	if got := readEvents(t, sc, 2); strings.Join(got, ",") != "custom:line1,custom:line2" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?lastEventId=7", nil)
+	if id := LastEventID(r); id != "7" {
+		t.Fatalf("query got %q", id)
+	}
+	r.Header.Set("Last-Event-ID", "9")
+	if id := LastEventID(r); id != "9" {
+		t.Fatalf("header got %q", id)
+	}
+}
+
+// plainWriter store response without flush support
+type plainWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *plainWriter) WriteHeader(status int)      { w.status = status }
+
+func TestStreamNoFlush(t *testing.T) {
+	w := &plainWriter{header: make(http.Header)}
+	_, err := NewStream(w, httptest.NewRequest("GET", "/", nil), Options{})
+	if err != ErrNoFlush || w.status != 0 || len(w.header) != 0 {
+		t.Fatalf("got %v with status %d and header %v", err, w.status, w.header)
+	}
+	// The handler responds with an error status
+	h := &Handler{Serve: func(*Stream, *http.Request) {}}
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.status != 500 {
+		t.Fatalf("handler status %d", w.status)
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	w := httptest.NewRecorder()
+	s, err := NewStream(w, httptest.NewRequest("GET", "/", nil), Options{Retry: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Close()
+		}()
+	}
+	wg.Wait()
+	<-s.Done()
+	if err := s.Send(Event{Data: "late"}); err != nil || strings.Contains(w.Body.String(), "late") {
+		t.Fatalf("send after close wrote %q", w.Body.String())
+	}
+	if w.Body.String() != "retry: 1000\n\n" {
+		t.Fatalf("got %q", w.Body.String())
+	}
+}
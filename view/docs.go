@@ -0,0 +1,48 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package view renders html/template files loaded from an fs.FS with layout
+inheritance, partials, and blocks. Parsed templates are cached in production
+and re-parsed whenever the template files change in development.
+
+A page is wrapped by the default layout, or by the layout declared with the
+extends comment on the first line of the page. The extends comment is a
+template comment holding the word extends and the quoted layout name, such
+as extends "layouts/admin". Layouts may extend another layout in the same
+way. The page overrides blocks defined by its layouts.
+
+	{{define "title"}}Users{{end}}
+	{{define "content"}}{{template "partials/table.html" .}}{{end}}
+
+Every template under the partials directory is available on every page by its
+file name. Create the view once and render pages from handlers.
+
+	views := view.New(os.DirFS("templates"), view.Options{
+		Layout:      "layouts/main",
+		Partials:    "partials",
+		Development: true,
+	})
+
+	func show(w http.ResponseWriter, r *http.Request) {
+		views.Render(w, r, 200, "users/show", user)
+	}
+
+Functions that need the current request are registered as RequestFuncs and
+bound on every Render. Validators check the constant arguments of template
+function calls when templates are parsed, for example to fail on startup when
+a template links to an unknown route.
+
+	views := view.New(os.DirFS("templates"), view.Options{
+		Funcs:        router.FuncMap(),
+		RequestFuncs: router.RequestFuncMap(),
+		Validators:   router.Validators(),
+	})
+	if err := views.Load("pages"); err != nil {
+		log.Fatal(err)
+	}
+*/
+package view
+
+// This file is intentionally left blank for Godoc documentation.
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package view
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"text/template/parse"
+)
+
+// ErrUnboundFunc represents request bound function called outside request
+var ErrUnboundFunc = errors.New("view: Template function requires a request")
+
+// RequestFuncMap define template functions bound to the current request.
+// Each function receives the request and returns the template function.
+type RequestFuncMap map[string]func(*http.Request) interface{}
+
+// unboundFuncs gets placeholders of request bound functions for parsing,
+// the lock must be held.
+func (v *View) unboundFuncs() template.FuncMap {
+	funcs := template.FuncMap{}
+	for k := range v.Options.RequestFuncs {
+		funcs[k] = func(...interface{}) (interface{}, error) {
+			return nil, ErrUnboundFunc
+		}
+	}
+	return funcs
+}
+
+// bindFuncs gets request bound functions for the request, the lock must be
+// held.
+func (v *View) bindFuncs(r *http.Request) template.FuncMap {
+	funcs := template.FuncMap{}
+	for k, f := range v.Options.RequestFuncs {
+		funcs[k] = f(r)
+	}
+	return funcs
+}
+
+// validate runs validators on every named function call with constant
+// string as its first argument.
+func (v *View) validate(p *page) error {
+	if len(v.Validators) == 0 {
+		return nil
+	}
+	for _, t := range p.tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		var err error
+		walk(t.Tree.Root, func(cmd *parse.CommandNode) {
+			if err != nil || len(cmd.Args) < 2 {
+				return
+			}
+			ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+			if !ok {
+				return
+			}
+			check, ok := v.Validators[ident.Ident]
+			if !ok {
+				return
+			}
+			if arg, ok := cmd.Args[1].(*parse.StringNode); ok {
+				if e := check(arg.Text); e != nil {
+					err = fmt.Errorf("view: %s calls %s %q: %w", t.Name(), ident.Ident, arg.Text, e)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk visits every command node under the parse tree node.
+func walk(node parse.Node, f func(*parse.CommandNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walk(c, f)
+		}
+	case *parse.ActionNode:
+		walk(n.Pipe, f)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walk(c, f)
+		}
+	case *parse.CommandNode:
+		f(n)
+		for _, c := range n.Args {
+			walk(c, f)
+		}
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.TemplateNode:
+		walk(n.Pipe, f)
+	}
+}
+
+// walkBranch visits command nodes of if, range, and with actions.
+func walkBranch(n *parse.BranchNode, f func(*parse.CommandNode)) {
+	walk(n.Pipe, f)
+	walk(n.List, f)
+	walk(n.ElseList, f)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package view
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mandala/omnibus/route"
+)
+
+func routeView(link string) *View {
+	router := route.NewRouter()
+	router.GetFunc("/users/{id}", func(http.ResponseWriter, *http.Request) {}).Name("user")
+	fsys := fstest.MapFS{
+		"pages/link.html": {Data: []byte(link)},
+	}
+	return New(fsys, Options{
+		Funcs:        router.FuncMap(),
+		RequestFuncs: router.RequestFuncMap(),
+		Validators:   router.Validators(),
+	})
+}
+
+func TestLoadRouteNames(t *testing.T) {
+	tests := []struct {
+		link string
+		err  bool
+	}{
+		{`{{url "user" "id" 1}}`, false},
+		{`{{absurl "user" "id" 1}}`, false},
+		{`{{if .}}{{url "users" "id" 1}}{{end}}`, true},
+		{`{{range .}}{{absurl "profile"}}{{end}}`, true},
+	}
+	for _, tt := range tests {
+		err := routeView(tt.link).Load("pages")
+		if tt.err && !errors.Is(err, route.ErrRouteNotFound) || !tt.err && err != nil {
+			t.Errorf("%s: got %v", tt.link, err)
+		}
+	}
+}
+
+func TestRenderRouteURL(t *testing.T) {
+	v := routeView(`{{url "user" "id" . "tab" "posts"}} {{absurl "user" "id" .}}`)
+	w := httptest.NewRecorder()
+	if err := v.Render(w, httptest.NewRequest("GET", "http://example.com/", nil), 200, "pages/link", 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != "/users/3?tab=posts http://example.com/users/3" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package view
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLayoutLoop represents layouts that extend each other in a cycle
+var ErrLayoutLoop = errors.New("view: Layout extends itself")
+
+// extendsPattern matches the extends comment on top of a template file
+var extendsPattern = regexp.MustCompile(`^\s*{{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?}}`)
+
+// Options store view related configurations
+type Options struct {
+	// Layout is the default layout for pages without extends comment
+	Layout string
+	// Partials is the directory of templates included on every page
+	Partials string
+	// Ext is the template file extension, defaults to .html
+	Ext string
+	// Development re-parses templates when the template files changed
+	Development bool
+	// Funcs is the template function map available on every page
+	Funcs template.FuncMap
+	// RequestFuncs is the template functions bound to the current request
+	RequestFuncs RequestFuncMap
+	// Validators checks constant first argument of the named template
+	// function calls when templates are parsed
+	Validators map[string]func(string) error
+}
+
+// View store parsed templates loaded from the file system
+type View struct {
+	Options
+	fsys  fs.FS
+	mu    sync.RWMutex
+	cache map[string]*page
+	pool  sync.Pool
+}
+
+// page store parsed template set of a single page
+type page struct {
+	tmpl     *template.Template
+	root     string
+	stamp    map[string]time.Time
+	partials int
+	// unbound store placeholders of request bound functions, and clones
+	// store executed copies of the template, which are escaped once and
+	// rebound to each request
+	unbound template.FuncMap
+	clones  sync.Pool
+}
+
+// New creates view that loads templates from the file system
+func New(fsys fs.FS, o Options) *View {
+	if o.Ext == "" {
+		o.Ext = ".html"
+	}
+	funcs := template.FuncMap{}
+	for k, f := range o.Funcs {
+		funcs[k] = f
+	}
+	o.Funcs = funcs
+	requestFuncs := RequestFuncMap{}
+	for k, f := range o.RequestFuncs {
+		requestFuncs[k] = f
+	}
+	o.RequestFuncs = requestFuncs
+	return &View{
+		Options: o,
+		fsys:    fsys,
+		cache:   make(map[string]*page),
+		pool: sync.Pool{
+			New: func() interface{} { return new(bytes.Buffer) },
+		},
+	}
+}
+
+// Funcs adds template functions to every page and drops parsed templates.
+func (v *View) Funcs(funcs template.FuncMap) *View {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, f := range funcs {
+		v.Options.Funcs[k] = f
+	}
+	v.cache = make(map[string]*page)
+	return v
+}
+
+// RequestFuncs adds request bound template functions to every page and
+// drops parsed templates.
+func (v *View) RequestFuncs(funcs RequestFuncMap) *View {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, f := range funcs {
+		v.Options.RequestFuncs[k] = f
+	}
+	v.cache = make(map[string]*page)
+	return v
+}
+
+// Load parses all pages under the directory ahead of time so template
+// errors surface on startup instead of on the first request. Layouts and
+// partials are parsed together with the pages.
+func (v *View) Load(dir string) error {
+	return fs.WalkDir(v.fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != v.Ext || !v.isPage(name) {
+			return err
+		}
+		_, err = v.lookup(name)
+		return err
+	})
+}
+
+// Execute applies the named page template to data and writes the output.
+// Request bound template functions fail when called from Execute.
+func (v *View) Execute(wr io.Writer, name string, data interface{}) error {
+	return v.execute(wr, nil, name, data)
+}
+
+// execute applies the named page template with functions bound to request.
+func (v *View) execute(wr io.Writer, r *http.Request, name string, data interface{}) error {
+	p, err := v.lookup(name)
+	if err != nil {
+		return err
+	}
+	if len(p.unbound) == 0 {
+		return p.tmpl.ExecuteTemplate(wr, p.root, data)
+	}
+	// Keep parsed template unexecuted so it can be cloned, executing a
+	// clone escapes it once and it is reused by later requests
+	tmpl, ok := p.clones.Get().(*template.Template)
+	if !ok {
+		if tmpl, err = p.tmpl.Clone(); err != nil {
+			return err
+		}
+	}
+	if r != nil {
+		v.mu.RLock()
+		funcs := v.bindFuncs(r)
+		v.mu.RUnlock()
+		tmpl.Funcs(funcs)
+	}
+	err = tmpl.ExecuteTemplate(wr, p.root, data)
+	// Drop functions of the request before the clone is reused
+	tmpl.Funcs(p.unbound)
+	p.clones.Put(tmpl)
+	return err
+}
+
+// Render executes the named page template and writes it as HTML response
+// with the status code. Nothing is written when the template fails, so the
+// caller can still send an error page.
+func (v *View) Render(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) error {
+	buf := v.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer v.pool.Put(buf)
+	if err := v.execute(buf, r, name, data); err != nil {
+		return err
+	}
+	// Set default content type unless set by handler
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	if r != nil && r.Method == "HEAD" {
+		return nil
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// filename normalizes template name into its file path.
+func (v *View) filename(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if path.Ext(name) == "" {
+		name += v.Ext
+	}
+	return name
+}
+
+// isPage checks whether the template file is a page instead of a layout or
+// a partial. Only pages are wrapped by the default layout.
+func (v *View) isPage(name string) bool {
+	if v.Layout != "" {
+		layout := v.filename(v.Layout)
+		if dir := path.Dir(layout); name == layout || dir != "." && strings.HasPrefix(name, dir+"/") {
+			return false
+		}
+	}
+	return v.Partials == "" || !strings.HasPrefix(name, path.Clean(v.Partials)+"/")
+}
+
+// lookup gets parsed page from cache or parses it.
+func (v *View) lookup(name string) (*page, error) {
+	name = v.filename(name)
+	v.mu.RLock()
+	p, ok := v.cache[name]
+	v.mu.RUnlock()
+	if ok && !(v.Development && v.changed(p)) {
+		return p, nil
+	}
+	// Parse page and store it to the cache
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	p, err := v.parse(name)
+	if err != nil {
+		return nil, err
+	}
+	v.cache[name] = p
+	return p, nil
+}
+
+// parse reads the page, its layout chain and partials into template set.
+func (v *View) parse(name string) (*page, error) {
+	p := &page{stamp: make(map[string]time.Time)}
+	// Resolve layout chain from the page up to the root layout
+	var chain []string
+	var sources []string
+	seen := make(map[string]bool)
+	for file := name; file != ""; {
+		if seen[file] {
+			return nil, ErrLayoutLoop
+		}
+		seen[file] = true
+		src, err := v.read(file, p)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, file)
+		sources = append(sources, src)
+		// Follow extends comment or the default layout for the page
+		if m := extendsPattern.FindStringSubmatch(src); m != nil {
+			file = v.filename(m[1])
+		} else if file == name && v.Layout != "" && v.isPage(name) {
+			file = v.filename(v.Layout)
+		} else {
+			file = ""
+		}
+	}
+	p.root = chain[len(chain)-1]
+	p.unbound = v.unboundFuncs()
+	p.tmpl = template.New("").Funcs(p.unbound).Funcs(v.Options.Funcs)
+	// Parse partials and layouts before the page to let it override blocks
+	if err := v.parsePartials(p); err != nil {
+		return nil, err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if _, err := p.tmpl.New(chain[i]).Parse(sources[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := v.validate(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parsePartials parses every template under the partials directory.
+func (v *View) parsePartials(p *page) error {
+	if v.Partials == "" {
+		return nil
+	}
+	return fs.WalkDir(v.fsys, v.Partials, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != v.Ext {
+			return err
+		}
+		p.partials++
+		src, err := v.read(name, p)
+		if err != nil {
+			return err
+		}
+		_, err = p.tmpl.New(name).Parse(src)
+		return err
+	})
+}
+
+// read gets template file content and records its modification time.
+func (v *View) read(name string, p *page) (string, error) {
+	b, err := fs.ReadFile(v.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	if info, err := fs.Stat(v.fsys, name); err == nil {
+		p.stamp[name] = info.ModTime()
+	}
+	return string(b), nil
+}
+
+// changed checks whether any file of the page was modified, added or
+// removed since the page was parsed.
+func (v *View) changed(p *page) bool {
+	for name, stamp := range p.stamp {
+		info, err := fs.Stat(v.fsys, name)
+		if err != nil || !info.ModTime().Equal(stamp) {
+			return true
+		}
+	}
+	if v.Partials == "" {
+		return false
+	}
+	// Count partials to detect added files
+	count := 0
+	fs.WalkDir(v.fsys, v.Partials, func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && path.Ext(name) == v.Ext {
+			count++
+		}
+		return nil
+	})
+	return count != p.partials
+}
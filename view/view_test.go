@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package view
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/main.html":  {Data: []byte(`<main>{{block "content" .}}{{end}}</main>`)},
+		"layouts/admin.html": {Data: []byte(`{{/* extends "layouts/main" */}}{{define "content"}}<admin>{{block "body" .}}{{end}}</admin>{{end}}`)},
+		"partials/name.html": {Data: []byte(`<b>{{.}}</b>`)},
+		"pages/home.html":    {Data: []byte(`{{define "content"}}Hi {{template "partials/name.html" .}}{{end}}`)},
+		"pages/panel.html":   {Data: []byte(`{{/* extends "layouts/admin" */}}{{define "body"}}{{.}}{{end}}`)},
+		"pages/path.html":    {Data: []byte(`{{define "content"}}{{path}}{{end}}`)},
+		"loop/a.html":        {Data: []byte(`{{/* extends "loop/b" */}}`)},
+		"loop/b.html":        {Data: []byte(`{{/* extends "loop/a" */}}`)},
+	}
+}
+
+func testView(fsys fstest.MapFS, o Options) *View {
+	o.Layout, o.Partials = "layouts/main", "partials"
+	return New(fsys, o)
+}
+
+func execute(t *testing.T, v *View, name string, data interface{}) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := v.Execute(&b, name, data); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestLayouts(t *testing.T) {
+	v := testView(testFS(), Options{})
+	if got := execute(t, v, "pages/home", "<Ann>"); got != "<main>Hi <b>&lt;Ann&gt;</b></main>" {
+		t.Fatalf("default layout got %q", got)
+	}
+	if got := execute(t, v, "/pages/panel.html", "Bob"); got != "<main><admin>Bob</admin></main>" {
+		t.Fatalf("extended layout got %q", got)
+	}
+	if err := v.Execute(&bytes.Buffer{}, "loop/a", nil); err != ErrLayoutLoop {
+		t.Fatalf("layout loop got %v", err)
+	}
+	if err := v.Load("pages"); err == nil || !strings.Contains(err.Error(), "path") {
+		t.Fatalf("load got %v", err)
+	}
+}
+
+func TestDevelopmentReload(t *testing.T) {
+	fsys := testFS()
+	for _, dev := range []bool{false, true} {
+		v := testView(fsys, Options{Development: dev})
+		fsys["partials/name.html"] = &fstest.MapFile{Data: []byte(`<b>{{.}}</b>`)}
+		execute(t, v, "pages/home", "Ann")
+		fsys["partials/name.html"] = &fstest.MapFile{Data: []byte(`<i>{{.}}</i>`), ModTime: time.Now()}
+		want := "<main>Hi <b>Ann</b></main>"
+		if dev {
+			want = "<main>Hi <i>Ann</i></main>"
+		}
+		if got := execute(t, v, "pages/home", "Ann"); got != want {
+			t.Fatalf("development %v got %q", dev, got)
+		}
+	}
+	// Added partials are picked up in development
+	v := testView(fsys, Options{Development: true})
+	execute(t, v, "pages/home", "Ann")
+	fsys["partials/new.html"] = &fstest.MapFile{Data: []byte(`new`)}
+	fsys["pages/home.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{template "partials/new.html"}}{{end}}`), ModTime: time.Now()}
+	if got := execute(t, v, "pages/home", nil); got != "<main>new</main>" {
+		t.Fatalf("added partial got %q", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	v := testView(testFS(), Options{
+		RequestFuncs: RequestFuncMap{
+			"path": func(r *http.Request) interface{} {
+				return func() string { return r.URL.Path }
+			},
+		},
+	})
+	w := httptest.NewRecorder()
+	if err := v.Render(w, httptest.NewRequest("GET", "/a", nil), 201, "pages/path", nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 201 || w.Body.String() != "<main>/a</main>" || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = httptest.NewRecorder()
+	v.Render(w, httptest.NewRequest("HEAD", "/a", nil), 200, "pages/path", nil)
+	if w.Body.Len() != 0 {
+		t.Fatalf("HEAD got %q", w.Body.String())
+	}
+	// Failed templates write nothing
+	w = httptest.NewRecorder()
+	if err := v.Render(w, httptest.NewRequest("GET", "/", nil), 200, "pages/missing", nil); err == nil || w.Code != 200 || w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Fatalf("got %v with %d %q", err, w.Code, w.Body.String())
+	}
+	// Request functions fail without request
+	if err := v.Execute(&bytes.Buffer{}, "pages/path", nil); !errors.Is(err, ErrUnboundFunc) {
+		t.Fatalf("execute got %v", err)
+	}
+}
+
+func TestRenderConcurrent(t *testing.T) {
+	v := testView(testFS(), Options{})
+	v.RequestFuncs(RequestFuncMap{
+		"path": func(r *http.Request) interface{} {
+			return func() string { return r.URL.Path }
+		},
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/p%d", i)
+			for j := 0; j < 20; j++ {
+				w := httptest.NewRecorder()
+				if err := v.Render(w, httptest.NewRequest("GET", path, nil), 200, "pages/path", nil); err != nil {
+					t.Error(err)
+					return
+				}
+				if w.Body.String() != "<main>"+path+"</main>" {
+					t.Errorf("got %q, want %s", w.Body.String(), path)
+					return
+				}
+			}
+		}(i)
+		if i == 16 {
+			v.Funcs(template.FuncMap{"upper": strings.ToUpper})
+			v.RequestFuncs(RequestFuncMap{
+				"method": func(r *http.Request) interface{} {
+					return func() string { return r.Method }
+				},
+			})
+		}
+	}
+	wg.Wait()
+}
+
+func TestValidators(t *testing.T) {
+	fsys := testFS()
+	fsys["pages/link.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{if .}}{{url "missing"}}{{end}}{{end}}`)}
+	v := testView(fsys, Options{
+		Funcs: template.FuncMap{"url": func(string) string { return "" }},
+		Validators: map[string]func(string) error{
+			"url": func(name string) error {
+				return fmt.Errorf("unknown route %s", name)
+			},
+		},
+	})
+	err := v.Execute(&bytes.Buffer{}, "pages/link", nil)
+	if err == nil || !strings.Contains(err.Error(), `calls url "missing": unknown route missing`) {
+		t.Fatalf("got %v", err)
+	}
+}
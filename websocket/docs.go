@@ -0,0 +1,31 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+/*
+Package websocket serves RFC 6455 WebSocket endpoints on top of
+gorilla/websocket. Handlers are registered like any other route, so route
+middleware such as authentication runs during the handshake. It adds origin
+checks, subprotocol negotiation, ping/pong keepalive, per-message deflate,
+and message size limits.
+
+	router.Get("/chat", websocket.New(websocket.Options{
+		Origins:      []string{"https://app.example.com"},
+		Subprotocols: []string{"chat.v1"},
+		Compression:  true,
+	}, func(c *websocket.Conn, r *http.Request) {
+		for {
+			kind, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(kind, msg)
+		}
+	})).Authenticate(jwt)
+
+Connections are tracked by serve.Server, which sends going away close frames
+and waits for the closing handshake when it is stopped.
+*/
+package websocket
+
+// This file is intentionally left blank for Godoc documentation.
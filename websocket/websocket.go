@@ -0,0 +1,228 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/mandala/omnibus/route"
+	"github.com/mandala/omnibus/serve"
+)
+
+// Message types of RFC 6455
+const (
+	TextMessage   = gorilla.TextMessage
+	BinaryMessage = gorilla.BinaryMessage
+	CloseMessage  = gorilla.CloseMessage
+	PingMessage   = gorilla.PingMessage
+	PongMessage   = gorilla.PongMessage
+)
+
+// Close codes of RFC 6455
+const (
+	CloseNormalClosure = gorilla.CloseNormalClosure
+	CloseGoingAway     = gorilla.CloseGoingAway
+	CloseMessageTooBig = gorilla.CloseMessageTooBig
+)
+
+// IsCloseError checks whether the error is close frame with any of the
+// codes.
+var IsCloseError = gorilla.IsCloseError
+
+// Options store WebSocket endpoint configurations
+type Options struct {
+	// Origins allowed to connect besides the request host, "*" allows any
+	// origin and "https://*.example.com" allows subdomains. Wildcards
+	// without scheme match the request scheme.
+	Origins []string
+	// Subprotocols supported by the server in order of preference
+	Subprotocols []string
+	// Compression negotiates per-message deflate extension
+	Compression bool
+	// ReadLimit caps size of received messages, defaults to 1 MiB
+	ReadLimit int64
+	// PingInterval between keepalive pings, defaults to 30 seconds
+	PingInterval time.Duration
+	// PongTimeout closes connections without pong reply, defaults to 10
+	// seconds
+	PongTimeout      time.Duration
+	HandshakeTimeout time.Duration
+	ReadBufferSize   int
+	WriteBufferSize  int
+}
+
+// Handler is the handler that upgrades requests to WebSocket connections.
+type Handler struct {
+	Options
+	Serve    func(*Conn, *http.Request)
+	upgrader gorilla.Upgrader
+}
+
+// New creates WebSocket handler serving connections with the function.
+func New(o Options, serve func(*Conn, *http.Request)) *Handler {
+	if o.ReadLimit <= 0 {
+		o.ReadLimit = 1 << 20
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = 30 * time.Second
+	}
+	if o.PongTimeout <= 0 {
+		o.PongTimeout = 10 * time.Second
+	}
+	h := &Handler{Options: o, Serve: serve}
+	h.upgrader = gorilla.Upgrader{
+		HandshakeTimeout:  o.HandshakeTimeout,
+		ReadBufferSize:    o.ReadBufferSize,
+		WriteBufferSize:   o.WriteBufferSize,
+		Subprotocols:      o.Subprotocols,
+		EnableCompression: o.Compression,
+		CheckOrigin:       h.checkOrigin,
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Upgrade(w, r)
+	if err != nil {
+		// Upgrader already responded with the handshake error
+		return
+	}
+	defer c.Close()
+	h.Serve(c, r)
+}
+
+// Upgrade upgrades the request to WebSocket connection.
+func (h *Handler) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(h.ReadLimit)
+	c := &Conn{Conn: conn, done: make(chan struct{})}
+	c.untrack = serve.Track(r, c)
+	c.keepalive(h.PingInterval, h.PongTimeout)
+	return c, nil
+}
+
+// checkOrigin allows requests without origin, from the request host, and
+// from the allowed origins.
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range h.Origins {
+		switch {
+		case o == "*":
+			return true
+		case strings.Contains(o, "*."):
+			if matchWildcard(o, u, route.RequestScheme(r)) {
+				return true
+			}
+		case strings.EqualFold(strings.TrimSuffix(o, "/"), origin):
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard checks origin URL against the subdomain wildcard, with the
+// default scheme if the wildcard has none.
+func matchWildcard(pattern string, u *url.URL, scheme string) bool {
+	if s, host, ok := strings.Cut(pattern, "://"); ok {
+		scheme, pattern = s, host
+	}
+	if !strings.HasPrefix(pattern, "*.") || !strings.EqualFold(u.Scheme, scheme) {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Host), strings.ToLower(strings.TrimSuffix(pattern[1:], "/")))
+}
+
+// Conn is WebSocket connection tracked for graceful shutdown.
+type Conn struct {
+	*gorilla.Conn
+	once    sync.Once
+	done    chan struct{}
+	untrack func()
+}
+
+// keepalive pings the peer periodically, the read deadline is extended
+// by every pong. The connection must be read for pongs to be handled.
+func (c *Conn) keepalive(interval, timeout time.Duration) {
+	c.SetReadDeadline(time.Now().Add(interval + timeout))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(interval + timeout))
+	})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.WriteControl(PingMessage, nil, time.Now().Add(timeout)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close closes the connection without closing handshake.
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		c.untrack()
+	})
+	return c.Conn.Close()
+}
+
+// Shutdown sends going away close frame and waits until the connection is
+// closed, or closes it once the context deadline passes. Without deadline,
+// the peer is given 5 seconds to reply. It implements serve.Tracked
+// interface.
+func (c *Conn) Shutdown(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	msg := gorilla.FormatCloseMessage(CloseGoingAway, "server shutdown")
+	if err := c.WriteControl(CloseMessage, msg, deadline); err != nil {
+		return c.Close()
+	}
+	// The reader receives the peer close frame and the handler closes
+	// the connection
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return nil
+	case <-timer.C:
+		return c.Close()
+	case <-ctx.Done():
+		c.Close()
+		return ctx.Err()
+	}
+}
+
+// Done gets channel closed once the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
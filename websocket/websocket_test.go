@@ -0,0 +1,233 @@
+// Copyright (c) 2017 Fadhli Dzil Ikram. All rights reserved.
+// This source code is brought to you under MIT license that can be found
+// on the LICENSE file.
+
+package websocket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/mandala/omnibus/serve"
+)
+
+// echo serves connections by echoing messages back.
+func echo(c *Conn, r *http.Request) {
+	for {
+		kind, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.WriteMessage(kind, msg)
+	}
+}
+
+// dial opens client connection to the HTTP URL.
+func dial(t *testing.T, url string, protocols ...string) (*gorilla.Conn, *http.Response) {
+	t.Helper()
+	d := gorilla.Dialer{Subprotocols: protocols}
+	c, res, err := d.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, res
+}
+
+// closeCode reads the connection until it gets close frame.
+func closeCode(t *testing.T, c *gorilla.Conn) int {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			if e, ok := err.(*gorilla.CloseError); ok {
+				return e.Code
+			}
+			t.Fatalf("got %v, want close frame", err)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	h := New(Options{Origins: []string{
+		"https://app.example.com/",
+		"https://*.example.org",
+		"*.example.net",
+	}}, echo)
+	tests := []struct {
+		url    string
+		origin string
+		ok     bool
+	}{
+		{"http://example.com/ws", "", true},
+		{"http://example.com/ws", "http://example.com", true},
+		{"http://example.com/ws", "https://EXAMPLE.com", true},
+		{"http://example.com/ws", "https://app.example.com", true},
+		{"http://example.com/ws", "http://app.example.com", false},
+		{"http://example.com/ws", "https://evil.com", false},
+		{"http://example.com/ws", "https://a.b.example.org", true},
+		{"http://example.com/ws", "http://a.example.org", false},
+		{"http://example.com/ws", "https://a.example.org:8443", false},
+		{"http://example.com/ws", "https://evilexample.org", false},
+		{"http://example.com/ws", "https://example.org", false},
+		{"https://example.com/ws", "https://a.example.net", true},
+		{"https://example.com/ws", "http://a.example.net", false},
+		{"http://example.com/ws", "http://a.example.net", true},
+		{"http://example.com/ws", "::", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if ok := h.checkOrigin(r); ok != tt.ok {
+			t.Errorf("%s from %q: got %v, want %v", tt.url, tt.origin, ok, tt.ok)
+		}
+	}
+	if !New(Options{Origins: []string{"*"}}, echo).checkOrigin(httptest.NewRequest("GET", "/", nil)) {
+		t.Error("any origin rejected")
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	s := httptest.NewServer(New(Options{Subprotocols: []string{"chat.v2", "chat.v1"}}, echo))
+	defer s.Close()
+	tests := []struct {
+		client []string
+		want   string
+	}{
+		{[]string{"chat.v1", "chat.v2"}, "chat.v2"},
+		{[]string{"chat.v1"}, "chat.v1"},
+		{[]string{"chat.v3"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		c, _ := dial(t, s.URL, tt.client...)
+		if c.Subprotocol() != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.client, c.Subprotocol(), tt.want)
+		}
+		c.WriteMessage(TextMessage, []byte("hi"))
+		if _, msg, err := c.ReadMessage(); err != nil || string(msg) != "hi" {
+			t.Errorf("%v: echo got %q %v", tt.client, msg, err)
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	conns := make(chan *Conn, 1)
+	s := httptest.NewServer(New(Options{}, func(c *Conn, r *http.Request) {
+		conns <- c
+		echo(c, r)
+	}))
+	defer s.Close()
+	client, _ := dial(t, s.URL)
+	c := <-conns
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result <- c.Shutdown(ctx)
+	}()
+	// The client replies the close frame, ending the handler
+	if code := closeCode(t, client); code != CloseGoingAway {
+		t.Fatalf("got close code %d", code)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown does not return after closing handshake")
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("connection is not closed")
+	}
+}
+
+func TestShutdownUnresponsive(t *testing.T) {
+	conns := make(chan *Conn, 1)
+	s := httptest.NewServer(New(Options{}, func(c *Conn, r *http.Request) {
+		conns <- c
+		echo(c, r)
+	}))
+	defer s.Close()
+	// The client never reads, so the close frame is not replied
+	dial(t, s.URL)
+	c := <-conns
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	c.Shutdown(ctx)
+	if time.Since(start) > time.Second {
+		t.Fatalf("shutdown took %v", time.Since(start))
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection is not closed after deadline")
+	}
+}
+
+func TestServerDrain(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	srv := serve.NewServer().WithOptions(serve.Options{
+		Address:         addr,
+		ShutdownTimeout: 5 * time.Second,
+		MaxInFlight:     1,
+	}).Use(New(Options{}, echo))
+	result := make(chan error, 1)
+	go func() {
+		result <- srv.Run()
+	}()
+	var clients []*gorilla.Conn
+	for i := 0; i < 100 && len(clients) < 2; i++ {
+		d := gorilla.Dialer{}
+		if c, _, err := d.Dial("ws://"+addr+"/", nil); err == nil {
+			t.Cleanup(func() { c.Close() })
+			clients = append(clients, c)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Both connections are open although MaxInFlight is one
+	if len(clients) != 2 || srv.Conns() != 2 {
+		t.Fatalf("got %d clients and %d tracked connections", len(clients), srv.Conns())
+	}
+	start := time.Now()
+	if err := srv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clients {
+		if code := closeCode(t, c); code != CloseGoingAway {
+			t.Fatalf("got close code %d", code)
+		}
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server does not stop")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("drain waited %v for the closing handshake", d)
+	}
+	if srv.Conns() != 0 {
+		t.Fatalf("%d connections still tracked", srv.Conns())
+	}
+}